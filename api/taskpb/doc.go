@@ -0,0 +1,8 @@
+// Package taskpb 是协调器任务服务（executor.task.v1.TaskService）的 protobuf 消息与 gRPC 代码，
+// 字段与 internal/coordinator 中的 TaskRequest/TaskResult 一一对应。服务端由 internal/adapters/contract
+// 的 GRPCClient 实现，其他服务通过 NewTaskServiceClient 远程提交任务并订阅结果。
+//
+// 修改 task.proto 后在本目录执行 go generate 重新生成（需要 protoc、protoc-gen-go 与 protoc-gen-go-grpc）。
+package taskpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative task.proto
@@ -6,10 +6,15 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"executor/internal/adapters/contract"
 	"executor/internal/adapters/ipfs"
+	"executor/internal/adapters/local"
+	"executor/internal/adapters/store"
 	"executor/internal/coordinator"
 )
 
@@ -25,21 +30,70 @@ func main() {
 		Namespace:     envOr("COORDINATOR_NAMESPACE", "default"),
 		ExecutorImage: envOr("COORDINATOR_EXECUTOR_IMAGE", "executor-demo/executor:demo"),
 		JobTemplate:   envOr("COORDINATOR_JOB_TEMPLATE", "k8s/job.yaml"),
+
+		MaxConcurrentTasks: envInt("COORDINATOR_MAX_CONCURRENT_TASKS", 4),
+		ModuleDelivery:     envOr("COORDINATOR_MODULE_DELIVERY", coordinator.DeliveryAuto),
+		ConfigMapLimit:     envInt("COORDINATOR_CONFIGMAP_LIMIT", 0),
+		PodGatewayURL:      envOr("COORDINATOR_POD_GATEWAY", ""),
+		FetchImage:         envOr("COORDINATOR_FETCH_IMAGE", ""),
+		DefaultTaskTimeout: envDuration("COORDINATOR_TASK_TIMEOUT", 0),
+		RecordRetention:    envDuration("COORDINATOR_RECORD_RETENTION", 0),
+		ArchiveResults:     envBool("COORDINATOR_ARCHIVE_RESULTS", false),
+		Retry: coordinator.RetryPolicy{
+			MaxAttempts:    envInt("COORDINATOR_RETRY_ATTEMPTS", 3),
+			InitialBackoff: envDuration("COORDINATOR_RETRY_BACKOFF", 500*time.Millisecond),
+			MaxBackoff:     envDuration("COORDINATOR_RETRY_MAX_BACKOFF", 30*time.Second),
+			Jitter:         envFloat("COORDINATOR_RETRY_JITTER", 0.2),
+		},
 	}
 	cfg.Log = logAdapter
 
-	kube, err := coordinator.NewKubeManager(cfg.Namespace, cfg.Log)
-	if err != nil {
-		logger.Fatalf("kube manager: %v", err)
+	if stateDir := envOr("COORDINATOR_STATE_DIR", ""); stateDir != "" {
+		taskStore, err := store.NewFileStore(stateDir, cfg.Log)
+		if err != nil {
+			logger.Fatalf("task store: %v", err)
+		}
+		cfg.Store = taskStore
+		logger.Printf("[INFO] persisting task state under %s", stateDir)
 	}
 
+	var backend coordinator.ExecutionBackend
+	switch mode := envOr("COORDINATOR_BACKEND", "kubernetes"); mode {
+	case "kubernetes":
+		kube, err := coordinator.NewKubeManager(cfg.Namespace, cfg.Log)
+		if err != nil {
+			logger.Fatalf("kube manager: %v", err)
+		}
+		backend = kube
+	case "local":
+		backend = local.NewBackend(cfg.Log)
+		logger.Printf("[INFO] running wasm modules in-process (local backend)")
+	default:
+		logger.Fatalf("unknown COORDINATOR_BACKEND %q (want kubernetes|local)", mode)
+	}
+
+	ipfsAPI := envOr("COORDINATOR_IPFS_API", "")
 	ipfsEndpoint := envOr("COORDINATOR_IPFS_ENDPOINT", "")
 	var ipfsClient coordinator.IPFSClient
-	if ipfsEndpoint != "" {
-		client, err := ipfs.NewGatewayClient(ipfsEndpoint, cfg.Log)
+	if ipfsAPI != "" {
+		client, err := ipfs.NewKuboClient(ipfsAPI, cfg.Log)
+		if err != nil {
+			logger.Fatalf("kubo rpc client: %v", err)
+		}
+		ipfsClient = client
+		logger.Printf("[INFO] using kubo rpc api %s", ipfsAPI)
+	} else if ipfsEndpoint != "" {
+		client, err := ipfs.NewGatewayClientWithOptions(strings.Split(ipfsEndpoint, ","), ipfs.GatewayOptions{
+			Format:  envOr("COORDINATOR_IPFS_FORMAT", ipfs.FormatRaw),
+			Race:    envInt("COORDINATOR_IPFS_RACE", 1),
+			Timeout: envDuration("COORDINATOR_IPFS_TIMEOUT", 0),
+		}, cfg.Log)
 		if err != nil {
 			logger.Fatalf("ipfs gateway client: %v", err)
 		}
+		if interval := envDuration("COORDINATOR_IPFS_STATS_INTERVAL", 5*time.Minute); interval > 0 {
+			go client.LogStats(ctx, interval)
+		}
 		ipfsClient = client
 		logger.Printf("[INFO] using ipfs gateway %s", ipfsEndpoint)
 	} else {
@@ -47,9 +101,86 @@ func main() {
 		ipfsClient = ipfs.NewPlaceholderClient(moduleDir, cfg.Log)
 		logger.Printf("[INFO] using local wasm mirror %s", moduleDir)
 	}
-	contractClient := contract.NewPlaceholderClient(cfg.Log)
+	if cacheDir := envOr("COORDINATOR_MODULE_CACHE_DIR", ""); cacheDir != "" {
+		cached, err := ipfs.NewCachingClient(ipfsClient, cacheDir, int64(envInt("COORDINATOR_MODULE_CACHE_SIZE", 1<<30)), cfg.Log)
+		if err != nil {
+			logger.Fatalf("module cache: %v", err)
+		}
+		ipfsClient = cached
+	}
+	// 任务来源只能选择一个：多个来源会相互覆盖，被丢弃来源的模块上传也会留在 ipfsClient 中。
+	var sources []string
+	for _, key := range []string{"COORDINATOR_TASK_FILE", "COORDINATOR_HTTP_ADDR", "COORDINATOR_GRPC_ADDR", "COORDINATOR_EVM_RPC"} {
+		if os.Getenv(key) != "" {
+			sources = append(sources, key)
+		}
+	}
+	if len(sources) > 1 {
+		logger.Fatalf("conflicting task sources %s; configure exactly one", strings.Join(sources, ", "))
+	}
+	var contractClient coordinator.ContractClient = contract.NewPlaceholderClient(cfg.Log)
+	if taskFile := envOr("COORDINATOR_TASK_FILE", ""); taskFile != "" {
+		fileClient, err := contract.NewJSONLClient(contract.JSONLConfig{
+			Path:         taskFile,
+			ResultsPath:  envOr("COORDINATOR_TASK_RESULTS", ""),
+			PollInterval: envDuration("COORDINATOR_TASK_POLL_INTERVAL", 0),
+		}, cfg.Log)
+		if err != nil {
+			logger.Fatalf("task file: %v", err)
+		}
+		contractClient = fileClient
+	}
+	if httpAddr := envOr("COORDINATOR_HTTP_ADDR", ""); httpAddr != "" {
+		httpClient, err := contract.NewHTTPClient(contract.HTTPConfig{
+			Addr:           httpAddr,
+			Token:          os.Getenv("COORDINATOR_HTTP_TOKEN"),
+			QueueSize:      envInt("COORDINATOR_HTTP_QUEUE_SIZE", 0),
+			MaxUploadBytes: int64(envInt("COORDINATOR_HTTP_MAX_UPLOAD", 0)),
+			ResultTTL:      envDuration("COORDINATOR_HTTP_RESULT_TTL", 0),
+		}, cfg.Log)
+		if err != nil {
+			logger.Fatalf("http task api: %v", err)
+		}
+		contractClient = httpClient
+		ipfsClient = httpClient.ModuleSource(ipfsClient)
+	}
+	if grpcAddr := envOr("COORDINATOR_GRPC_ADDR", ""); grpcAddr != "" {
+		grpcClient, err := contract.NewGRPCClient(contract.GRPCConfig{
+			Addr:  grpcAddr,
+			Token: os.Getenv("COORDINATOR_GRPC_TOKEN"),
+		}, cfg.Log)
+		if err != nil {
+			logger.Fatalf("grpc task service: %v", err)
+		}
+		contractClient = grpcClient
+		ipfsClient = grpcClient.ModuleSource(ipfsClient)
+	}
+	if rpcURL := envOr("COORDINATOR_EVM_RPC", ""); rpcURL != "" {
+		checkpoint := envOr("COORDINATOR_EVM_CHECKPOINT", "")
+		if stateDir := envOr("COORDINATOR_STATE_DIR", ""); checkpoint == "" && stateDir != "" {
+			checkpoint = filepath.Join(stateDir, "evm-checkpoint.json")
+		}
+		evmClient, err := contract.NewEVMClient(contract.EVMConfig{
+			RPCURL:         rpcURL,
+			Contract:       envOr("COORDINATOR_EVM_CONTRACT", ""),
+			PrivateKey:     os.Getenv("COORDINATOR_EVM_PRIVATE_KEY"),
+			ChainID:        uint64(envInt("COORDINATOR_EVM_CHAIN_ID", 0)),
+			FromBlock:      uint64(envInt("COORDINATOR_EVM_FROM_BLOCK", 0)),
+			Confirmations:  uint64(envInt("COORDINATOR_EVM_CONFIRMATIONS", 12)),
+			CheckpointPath: checkpoint,
+			PollInterval:   envDuration("COORDINATOR_EVM_POLL_INTERVAL", 0),
+			BlockRange:     uint64(envInt("COORDINATOR_EVM_BLOCK_RANGE", 0)),
+			GasLimit:       uint64(envInt("COORDINATOR_EVM_GAS_LIMIT", 0)),
+			ReceiptTimeout: envDuration("COORDINATOR_EVM_RECEIPT_TIMEOUT", 0),
+		}, cfg.Log)
+		if err != nil {
+			logger.Fatalf("evm contract client: %v", err)
+		}
+		contractClient = evmClient
+		logger.Printf("[INFO] using evm contract %s via %s as %s", envOr("COORDINATOR_EVM_CONTRACT", ""), rpcURL, evmClient.Address())
+	}
 
-	service, err := coordinator.NewCoordinator(cfg, contractClient, ipfsClient, kube)
+	service, err := coordinator.NewCoordinator(cfg, contractClient, ipfsClient, backend)
 	if err != nil {
 		logger.Fatalf("coordinator: %v", err)
 	}
@@ -85,3 +216,59 @@ func envOr(key, fallback string) string {
 	}
 	return fallback
 }
+
+// envInt 读取整数型环境变量，缺失或非法时返回默认值。
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("[WARN] invalid %s=%q, using %d", key, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+// envDuration 读取 time.ParseDuration 格式的环境变量，缺失或非法时返回默认值。
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("[WARN] invalid %s=%q, using %s", key, v, fallback)
+		return fallback
+	}
+	return d
+}
+
+// envFloat 读取浮点型环境变量，缺失或非法时返回默认值。
+func envFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("[WARN] invalid %s=%q, using %g", key, v, fallback)
+		return fallback
+	}
+	return f
+}
+
+// envBool 读取布尔型环境变量，缺失或非法时返回默认值。
+func envBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("[WARN] invalid %s=%q, using %t", key, v, fallback)
+		return fallback
+	}
+	return b
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -12,201 +13,168 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/tetratelabs/wazero"
-	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"executor/internal/wasmexec"
 )
 
-type executorConfig struct {
-	wasmPath   string
-	outputPath string
-	entry      string
-	inputPath  string
-	argsJSON   string
-}
-
-type inputSpec struct {
-	Entry string   `json:"entry"`
-	Args  []uint64 `json:"args"`
-}
+// maxTerminationMessage 是 kubelet 读取 termination message 的上限。
+const maxTerminationMessage = 4096
 
-type execOutput struct {
-	Entry   string   `json:"entry"`
-	Args    []uint64 `json:"args"`
-	Results []uint64 `json:"results"`
+type executorConfig struct {
+	wasmPath    string
+	wasmParts   int
+	outputPath  string
+	messagePath string
+	inputPath   string
+	dataPath    string
+	// resultConfigMap 是超过 termination message 上限的结果写入的 ConfigMap，由协调器创建。
+	resultConfigMap string
+	resultNamespace string
 }
 
 func getenvOr(key, def string) string {
-	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+	if v := getenv(key); v != "" {
 		return v
 	}
 	return def
 }
 
-func mustUint64(val, name string) uint64 {
-	u, err := strconv.ParseUint(val, 10, 64)
-	if err != nil {
-		log.Fatalf("invalid %s=%q: %v", name, val, err)
-	}
-	return u
+func getenv(key string) string {
+	return strings.TrimSpace(os.Getenv(key))
 }
 
 func main() {
 	cfg := loadConfig()
-	wasmBin, err := os.ReadFile(cfg.wasmPath)
+	wasmBin, err := readModule(cfg.wasmPath, cfg.wasmParts)
 	if err != nil {
 		log.Fatalf("read wasm from %s: %v", cfg.wasmPath, err)
 	}
 
-	ctx := context.Background()
-	rt := wazero.NewRuntime(ctx)
-	defer rt.Close(ctx)
-	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
-
-	mod, err := rt.InstantiateWithConfig(ctx, wasmBin, wazero.NewModuleConfig().WithStartFunctions("_initialize"))
+	input, err := readInput(cfg.inputPath)
 	if err != nil {
-		log.Fatalf("instantiate wasm: %v", err)
+		log.Fatalf("read input: %v", err)
 	}
-
-	entry, args, err := resolveInvocation(cfg)
+	inv, err := wasmexec.ResolveInvocation(input, getenv)
 	if err != nil {
 		log.Fatalf("resolve invocation: %v", err)
 	}
-	fn := mod.ExportedFunction(entry)
-	if fn == nil {
-		log.Fatalf("exported function %q not found", entry)
+	limits, err := wasmexec.ParseLimits(getenv)
+	if err != nil {
+		log.Fatalf("resolve limits: %v", err)
 	}
 
-	results, err := fn.Call(ctx, args...)
+	output, err := run(cfg, wasmBin, inv, limits)
 	if err != nil {
-		log.Fatalf("call %s failed: %v", entry, err)
+		log.Fatalf("%v", err)
 	}
 
-	output := execOutput{Entry: entry, Args: cloneSlice(args), Results: cloneSlice(results)}
-	if err := writeOutput(cfg.outputPath, output); err != nil {
+	if err := writeOutput(cfg, output); err != nil {
+		// 结果无法交付时改写为失败结果，协调器据此明确失败而不是从日志中猜测结果。
+		if werr := writeOutput(cfg, wasmexec.Failure(inv.Entry, fmt.Errorf("deliver result: %w", err))); werr != nil {
+			log.Printf("write failure output: %v", werr)
+		}
 		log.Fatalf("write output: %v", err)
 	}
-	log.Printf("entry=%s args=%v results=%v", entry, args, results)
+	log.Printf("entry=%s args=%v results=%v", output.Entry, output.Args, output.Results)
+}
+
+// run 执行模块；失败时同样写出 result.json，协调器据 status 区分超时、调用预算耗尽与普通错误。
+func run(cfg executorConfig, wasmBin []byte, inv wasmexec.Invocation, limits wasmexec.Limits) (wasmexec.Output, error) {
+	output, err := wasmexec.Run(context.Background(), wasmBin, inv, wasmexec.Options{Limits: limits, DataDir: cfg.dataPath})
+	if err != nil {
+		if werr := writeOutput(cfg, wasmexec.Failure(inv.Entry, err)); werr != nil {
+			log.Printf("write failure output: %v", werr)
+		}
+		return wasmexec.Output{}, err
+	}
+	return output, nil
 }
 
 func loadConfig() executorConfig {
 	return executorConfig{
-		wasmPath:   getenvOr("WASM_PATH", "host/wasm/module.wasm"),
-		outputPath: getenvOr("OUTPUT_PATH", "host/shared/result.txt"),
-		entry:      getenvOr("ENTRY", "add"),
-		inputPath:  getenvOr("INPUT_PATH", "/mnt/shared/input.json"),
-		argsJSON:   strings.TrimSpace(os.Getenv("ARGS_JSON")),
+		wasmPath:    getenvOr("WASM_PATH", "host/wasm/module.wasm"),
+		wasmParts:   mustInt(getenvOr("WASM_PARTS", "0"), "WASM_PARTS"),
+		outputPath:  getenvOr("OUTPUT_PATH", "host/shared/result.txt"),
+		messagePath: getenv("RESULT_MESSAGE_PATH"),
+		inputPath:   getenvOr("INPUT_PATH", "/mnt/shared/input.json"),
+		dataPath:    getenv("DATA_PATH"),
+
+		resultConfigMap: getenv("RESULT_CONFIGMAP"),
+		resultNamespace: getenvOr("RESULT_NAMESPACE", "default"),
 	}
 }
 
-func resolveInvocation(cfg executorConfig) (string, []uint64, error) {
-	spec, err := readInputSpec(cfg.inputPath)
-	if err != nil {
-		return "", nil, err
+func mustInt(val, name string) int {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 {
+		log.Fatalf("invalid %s=%q", name, val)
 	}
-	entry := cfg.entry
-	if spec.Entry != "" {
-		entry = spec.Entry
+	return n
+}
+
+// readModule 读取模块；parts > 0 时按 <path>.part-NNN 顺序拼接分片 ConfigMap 投递的模块。
+func readModule(path string, parts int) ([]byte, error) {
+	if parts == 0 {
+		return os.ReadFile(path)
 	}
-	args := append([]uint64{}, spec.Args...)
-	if len(args) == 0 && cfg.argsJSON != "" {
-		if err := json.Unmarshal([]byte(cfg.argsJSON), &args); err != nil {
-			return "", nil, fmt.Errorf("parse ARGS_JSON: %w", err)
+	var buf bytes.Buffer
+	for i := 0; i < parts; i++ {
+		chunk, err := os.ReadFile(fmt.Sprintf("%s.part-%03d", path, i))
+		if err != nil {
+			return nil, err
 		}
+		buf.Write(chunk)
 	}
-	if len(args) == 0 {
-		args = sequentialArgs()
-	}
-	if len(args) == 0 {
-		args = legacyAddArgs()
-	}
-	return entry, args, nil
+	return buf.Bytes(), nil
 }
 
-func readInputSpec(path string) (inputSpec, error) {
-	var spec inputSpec
+func readInput(path string) ([]byte, error) {
 	if path == "" {
-		return spec, nil
+		return nil, nil
 	}
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			return spec, nil
+			return nil, nil
 		}
-		return spec, fmt.Errorf("read %s: %w", path, err)
-	}
-	content := strings.TrimSpace(string(data))
-	if content == "" {
-		return spec, nil
-	}
-	if err := json.Unmarshal([]byte(content), &spec); err != nil {
-		return spec, fmt.Errorf("parse %s: %w", path, err)
+		return nil, fmt.Errorf("read %s: %w", path, err)
 	}
-	return spec, nil
+	return data, nil
 }
 
-func sequentialArgs() []uint64 {
-	var args []uint64
-	for i := 0; ; i++ {
-		candidates := []string{
-			fmt.Sprintf("ARG_%d", i),
-			fmt.Sprintf("ARG%d", i),
-		}
-		var (
-			val  string
-			name string
-		)
-		for _, c := range candidates {
-			if v := strings.TrimSpace(os.Getenv(c)); v != "" {
-				val = v
-				name = c
-				break
-			}
-		}
-		if val == "" {
-			break
-		}
-		args = append(args, mustUint64(val, name))
-	}
-	return args
-}
-
-func legacyAddArgs() []uint64 {
-	x := strings.TrimSpace(os.Getenv("ADD_X"))
-	y := strings.TrimSpace(os.Getenv("ADD_Y"))
-	if x == "" || y == "" {
-		return nil
-	}
-	return []uint64{mustUint64(x, "ADD_X"), mustUint64(y, "ADD_Y")}
-}
-
-func writeOutput(path string, out execOutput) error {
-	if out.Args == nil {
-		out.Args = []uint64{}
-	}
-	if out.Results == nil {
-		out.Results = []uint64{}
-	}
+func writeOutput(cfg executorConfig, out wasmexec.Output) error {
 	payload, err := json.Marshal(out)
 	if err != nil {
 		return err
 	}
-	if dir := filepath.Dir(path); dir != "." {
+	if dir := filepath.Dir(cfg.outputPath); dir != "." {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			return err
 		}
 	}
-	if err := os.WriteFile(path, append(payload, '\n'), 0o644); err != nil {
+	if err := os.WriteFile(cfg.outputPath, append(payload, '\n'), 0o644); err != nil {
 		return err
 	}
+	if cfg.messagePath != "" {
+		if err := publishResult(cfg, payload); err != nil {
+			return err
+		}
+	}
 	fmt.Println(string(payload))
 	return nil
 }
 
-func cloneSlice(src []uint64) []uint64 {
-	if len(src) == 0 {
-		return []uint64{}
+// publishResult 把 result.json 交给协调器：不超过 termination message 上限时写入 messagePath，
+// 否则写入 RESULT_CONFIGMAP 指定的结果 ConfigMap，两者都不可用时返回错误。
+func publishResult(cfg executorConfig, payload []byte) error {
+	if len(payload) <= maxTerminationMessage {
+		if err := os.WriteFile(cfg.messagePath, payload, 0o644); err != nil {
+			return fmt.Errorf("write termination message: %w", err)
+		}
+		return nil
+	}
+	if cfg.resultConfigMap == "" {
+		return fmt.Errorf("result is %d bytes, exceeds termination message limit %d and RESULT_CONFIGMAP is not set", len(payload), maxTerminationMessage)
 	}
-	dup := make([]uint64, len(src))
-	copy(dup, src)
-	return dup
+	log.Printf("result is %d bytes, writing to configmap %s", len(payload), cfg.resultConfigMap)
+	return writeResultConfigMap(cfg.resultNamespace, cfg.resultConfigMap, payload)
 }
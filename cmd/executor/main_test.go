@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"executor/internal/wasmexec"
+)
+
+// limitsModule 导出不含调用的死循环 spin() 与无限递归的 recurse()。
+var limitsModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// type: () -> ()
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+	// function: spin, recurse
+	0x03, 0x03, 0x02, 0x00, 0x00,
+	// export: spin, recurse
+	0x07, 0x12, 0x02,
+	0x04, 's', 'p', 'i', 'n', 0x00, 0x00,
+	0x07, 'r', 'e', 'c', 'u', 'r', 's', 'e', 0x00, 0x01,
+	// code: spin = loop br 0 end, recurse = call recurse
+	0x0a, 0x0e, 0x02,
+	0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b,
+	0x04, 0x00, 0x10, 0x01, 0x0b,
+}
+
+// TestRunWritesLimitStatus 确认超时与调用预算耗尽都写出带对应 status 的 result.json 与 termination message。
+func TestRunWritesLimitStatus(t *testing.T) {
+	for _, tc := range []struct {
+		entry  string
+		limits wasmexec.Limits
+		status string
+	}{
+		{"spin", wasmexec.Limits{Timeout: 200 * time.Millisecond}, wasmexec.StatusTimeout},
+		{"recurse", wasmexec.Limits{Timeout: 10 * time.Second, CallBudget: 1000}, wasmexec.StatusOutOfFuel},
+	} {
+		t.Run(tc.entry, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := executorConfig{
+				outputPath:  filepath.Join(dir, "result.json"),
+				messagePath: filepath.Join(dir, "termination-log"),
+			}
+			if _, err := run(cfg, limitsModule, wasmexec.Invocation{Entry: tc.entry}, tc.limits); err == nil {
+				t.Fatal("run succeeded")
+			}
+			for _, path := range []string{cfg.outputPath, cfg.messagePath} {
+				raw, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var out wasmexec.Output
+				if err := json.Unmarshal(raw, &out); err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+				if out.Status != tc.status || out.Entry != tc.entry || out.Error == "" {
+					t.Errorf("%s: status=%q entry=%q error=%q", filepath.Base(path), out.Status, out.Entry, out.Error)
+				}
+			}
+		})
+	}
+}
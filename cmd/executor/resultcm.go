@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// maxResultConfigMap 是写入结果 ConfigMap 的上限，为对象元数据预留 ConfigMap 1MiB 上限之外的余量。
+	maxResultConfigMap = 1000 << 10
+	// resultConfigMapKey 与协调器读取结果 ConfigMap 时使用的键一致。
+	resultConfigMapKey = "result.json"
+)
+
+// writeResultConfigMap 把 result.json 写入协调器预先创建的 ConfigMap，
+// 使用 Pod 的 ServiceAccount 凭据，需对该 ConfigMap 具有 patch 权限。
+func writeResultConfigMap(namespace, name string, payload []byte) error {
+	if len(payload) > maxResultConfigMap {
+		return fmt.Errorf("result is %d bytes, exceeds result configmap limit %d", len(payload), maxResultConfigMap)
+	}
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return fmt.Errorf("load in-cluster config: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]any{"data": map[string]string{resultConfigMapKey: string(payload)}})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := client.CoreV1().ConfigMaps(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("patch result configmap %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+package contract
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"executor/internal/cid"
+	"executor/internal/coordinator"
+)
+
+// API 任务来源（HTTP、gRPC）暴露的任务状态。
+const (
+	statusQueued    = "queued"
+	statusRunning   = "running"
+	statusSucceeded = "succeeded"
+	statusFailed    = "failed"
+	statusCancelled = "cancelled"
+)
+
+var (
+	errInvalidTask  = errors.New("invalid task")
+	errTaskExists   = errors.New("task already exists")
+	errQueueFull    = errors.New("task queue is full")
+	errTaskNotFound = errors.New("task not found")
+	errTaskFinished = errors.New("task already finished")
+	errCancelBusy   = errors.New("cancellation queue is full")
+)
+
+// taskBoard 是 API 任务来源共用的内存任务表：提交的任务进入有界队列等待协调器领取，
+// 状态变化时唤醒等待者，已结束的任务保留 ttl 后清理。上传的模块按引用计数保存到任务结束。
+// 运行中的任务被取消时经 cancels 通知协调器（见 coordinator.TaskCanceller）。
+type taskBoard struct {
+	name    string
+	log     coordinator.Logger
+	ttl     time.Duration
+	queue   chan coordinator.TaskRequest
+	cancels chan string
+
+	mu       sync.Mutex
+	tasks    map[string]*apiTask
+	modules  map[string]*upload // 上传的模块，以 raw CIDv1 为键
+	seq      uint64             // 每有任务结束递增，供 finishedSince 增量读取
+	finished chan struct{}      // 每有任务结束时关闭并替换
+}
+
+// upload 是一个上传的模块及引用它的未结束任务数，计数归零时删除。
+type upload struct {
+	data []byte
+	refs int
+}
+
+// apiTask 是一个通过 API 提交的任务的状态。changed 在每次状态变化时关闭并替换。
+type apiTask struct {
+	task        coordinator.TaskRequest
+	uploaded    bool
+	status      string
+	submittedAt time.Time
+	updatedAt   time.Time
+	result      *resultRecord
+	finishedSeq uint64
+	// cancelRequested 表示已请求协调器中止该任务，避免重复通知。
+	cancelRequested bool
+	changed         chan struct{}
+}
+
+// taskView 是任务状态的快照，同时是 HTTP API 返回的 JSON。
+type taskView struct {
+	TaskID      string        `json:"task_id"`
+	Status      string        `json:"status"`
+	WasmCID     string        `json:"wasm_cid"`
+	InputCID    string        `json:"input_cid,omitempty"`
+	DataCID     string        `json:"data_cid,omitempty"`
+	Entry       string        `json:"entry,omitempty"`
+	SubmittedAt time.Time     `json:"submitted_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Result      *resultRecord `json:"result,omitempty"`
+
+	request coordinator.TaskRequest
+}
+
+func newTaskBoard(name string, queueSize int, ttl time.Duration, log coordinator.Logger) *taskBoard {
+	return &taskBoard{
+		name:     name,
+		log:      log,
+		ttl:      ttl,
+		queue:    make(chan coordinator.TaskRequest, queueSize),
+		cancels:  make(chan string, queueSize),
+		tasks:    map[string]*apiTask{},
+		modules:  map[string]*upload{},
+		finished: make(chan struct{}),
+	}
+}
+
+// submit 登记任务并放入队列。module 非空时以其 raw CIDv1 作为 WasmCID；缺少 TaskID 时生成
+// "<name>-<随机十六进制>"。TaskID 重复时返回已有任务与 errTaskExists。
+func (b *taskBoard) submit(task coordinator.TaskRequest, module []byte) (taskView, error) {
+	if module != nil {
+		sum := cid.Sum(cid.Raw, module)
+		if task.WasmCID != "" {
+			if declared, err := cid.Parse(task.WasmCID); err != nil || !declared.Equals(sum) {
+				return taskView{}, fmt.Errorf("%w: wasm_cid must be omitted or equal the raw cid of the uploaded module (%s)", errInvalidTask, sum)
+			}
+		}
+		task.WasmCID = sum.String()
+	}
+	if task.WasmCID == "" {
+		return taskView{}, fmt.Errorf("%w: wasm_cid or module is required", errInvalidTask)
+	}
+	if task.TaskID == "" {
+		task.TaskID = newTaskID(b.name)
+	}
+
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pruneLocked(now)
+	if existing, ok := b.tasks[task.TaskID]; ok {
+		return existing.view(), errTaskExists
+	}
+	select {
+	case b.queue <- task:
+	default:
+		return taskView{}, errQueueFull
+	}
+	t := &apiTask{task: task, uploaded: module != nil, status: statusQueued, submittedAt: now, updatedAt: now, changed: make(chan struct{})}
+	b.tasks[task.TaskID] = t
+	if module != nil {
+		u, ok := b.modules[task.WasmCID]
+		if !ok {
+			u = &upload{data: module}
+			b.modules[task.WasmCID] = u
+		}
+		u.refs++
+	}
+	b.log.Infof("%s: task %s queued (cid=%s)", b.name, task.TaskID, task.WasmCID)
+	return t.view(), nil
+}
+
+// deliver 把队列中的任务交给协调器并标记为运行中；已取消的任务直接丢弃。
+// 等待协调器领取期间被取消的任务仍会交出，随后立即请求协调器中止。
+func (b *taskBoard) deliver(ctx context.Context, task coordinator.TaskRequest, out chan<- coordinator.TaskRequest) error {
+	if view, _, ok := b.snapshot(task.TaskID); !ok || view.Status != statusQueued {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case out <- task:
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.tasks[task.TaskID]; ok {
+		if t.status == statusCancelled {
+			b.requestCancelLocked(t)
+		}
+		b.setStatusLocked(t, statusRunning, nil)
+	}
+	return nil
+}
+
+// publish 保存结果并唤醒等待者，返回任务是否存在。被撤回的任务记为 cancelled。
+func (b *taskBoard) publish(result coordinator.TaskResult) bool {
+	status := statusFailed
+	switch {
+	case result.Success:
+		status = statusSucceeded
+	case result.FailureReason == coordinator.FailureCancelled:
+		status = statusCancelled
+	}
+	rec := newResultRecord(result)
+	if !b.setStatus(result.TaskID, status, &rec) {
+		b.log.Warnf("%s: result for unknown task %s dropped", b.name, result.TaskID)
+		return false
+	}
+	b.log.Infof("%s: task %s %s", b.name, result.TaskID, status)
+	return true
+}
+
+// cancel 取消任务：仍在排队的任务立即记为 cancelled，不再交给协调器；已交给协调器的任务
+// 通知协调器中止，状态在协调器上报 cancelled 结果后更新。
+func (b *taskBoard) cancel(taskID string) (taskView, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tasks[taskID]
+	switch {
+	case !ok:
+		return taskView{}, errTaskNotFound
+	case t.done():
+		return t.view(), errTaskFinished
+	case t.status == statusQueued:
+		b.setStatusLocked(t, statusCancelled, nil)
+		b.log.Infof("%s: task %s cancelled", b.name, taskID)
+	case !t.cancelRequested:
+		if !b.requestCancelLocked(t) {
+			return t.view(), errCancelBusy
+		}
+		b.log.Infof("%s: cancellation of running task %s requested", b.name, taskID)
+	}
+	return t.view(), nil
+}
+
+// requestCancelLocked 通知协调器中止已领取的任务。
+func (b *taskBoard) requestCancelLocked(t *apiTask) bool {
+	select {
+	case b.cancels <- t.task.TaskID:
+		t.cancelRequested = true
+		return true
+	default:
+		return false
+	}
+}
+
+// subscribeCancellations 把运行中任务的取消请求转交给协调器。
+func (b *taskBoard) subscribeCancellations(ctx context.Context, out chan<- string) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case id := <-b.cancels:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case out <- id:
+			}
+		}
+	}
+}
+
+// setStatus 更新任务状态并通知等待者；终态不会被覆盖。返回任务是否存在。
+func (b *taskBoard) setStatus(taskID, status string, result *resultRecord) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tasks[taskID]
+	if ok {
+		b.setStatusLocked(t, status, result)
+	}
+	return ok
+}
+
+func (b *taskBoard) setStatusLocked(t *apiTask, status string, result *resultRecord) {
+	if t.done() || (t.status == status && result == nil) {
+		return
+	}
+	t.status = status
+	t.updatedAt = time.Now()
+	if result != nil {
+		t.result = result
+	}
+	if t.done() {
+		if t.uploaded {
+			b.releaseLocked(t.task.WasmCID)
+		}
+		b.seq++
+		t.finishedSeq = b.seq
+		close(b.finished)
+		b.finished = make(chan struct{})
+	}
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// snapshot 返回任务当前状态与下一次变化的通知通道。
+func (b *taskBoard) snapshot(taskID string) (taskView, <-chan struct{}, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tasks[taskID]
+	if !ok {
+		return taskView{}, nil, false
+	}
+	return t.view(), t.changed, true
+}
+
+// watch 返回当前的结束序号与下一次有任务结束的通知通道。
+func (b *taskBoard) watch() (uint64, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq, b.finished
+}
+
+// finishedSince 按结束顺序返回序号大于 seq 的已结束任务、当前序号与下一次有任务结束的通知通道。
+func (b *taskBoard) finishedSince(seq uint64) ([]taskView, uint64, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var finished []*apiTask
+	for _, t := range b.tasks {
+		if t.finishedSeq > seq {
+			finished = append(finished, t)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].finishedSeq < finished[j].finishedSeq })
+	views := make([]taskView, len(finished))
+	for i, t := range finished {
+		views[i] = t.view()
+	}
+	return views, b.seq, b.finished
+}
+
+// pruneLocked 清理超过 ttl 的已结束任务。
+func (b *taskBoard) pruneLocked(now time.Time) {
+	for id, t := range b.tasks {
+		if t.done() && now.Sub(t.updatedAt) > b.ttl {
+			delete(b.tasks, id)
+		}
+	}
+}
+
+// releaseLocked 减少上传模块的引用计数，归零时释放。
+func (b *taskBoard) releaseLocked(wasmCID string) {
+	if u, ok := b.modules[wasmCID]; ok {
+		if u.refs--; u.refs <= 0 {
+			delete(b.modules, wasmCID)
+		}
+	}
+}
+
+func (b *taskBoard) module(ref string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	u, ok := b.modules[strings.TrimSpace(ref)]
+	if !ok {
+		return nil, false
+	}
+	return u.data, true
+}
+
+func (t *apiTask) done() bool {
+	return terminal(t.status)
+}
+
+func (t *apiTask) view() taskView {
+	return taskView{
+		TaskID:      t.task.TaskID,
+		Status:      t.status,
+		WasmCID:     t.task.WasmCID,
+		InputCID:    t.task.InputCID,
+		DataCID:     t.task.DataCID,
+		Entry:       t.task.Entry,
+		SubmittedAt: t.submittedAt,
+		UpdatedAt:   t.updatedAt,
+		Result:      t.result,
+		request:     t.task,
+	}
+}
+
+func terminal(status string) bool {
+	return status == statusSucceeded || status == statusFailed || status == statusCancelled
+}
+
+func newTaskID(prefix string) string {
+	var b [8]byte
+	rand.Read(b[:])
+	return prefix + "-" + hex.EncodeToString(b[:])
+}
+
+// uploadedModules 优先返回通过 API 上传的模块（内容在上传时已按 CID 计算），其余请求交给下游客户端。
+type uploadedModules struct {
+	board *taskBoard
+	inner coordinator.IPFSClient
+}
+
+func (u *uploadedModules) FetchModule(ctx context.Context, ref string) ([]byte, error) {
+	if module, ok := u.board.module(ref); ok {
+		return module, nil
+	}
+	return u.inner.FetchModule(ctx, ref)
+}
+
+// Pin 跳过上传的模块（它们不在 IPFS 网络中），其余交给下游第一个支持固定的客户端。
+func (u *uploadedModules) Pin(ctx context.Context, ref string) error {
+	if _, ok := u.board.module(ref); ok {
+		return nil
+	}
+	for c := u.inner; c != nil; {
+		if p, ok := c.(coordinator.IPFSPinner); ok {
+			return p.Pin(ctx, ref)
+		}
+		w, ok := c.(interface{ Unwrap() coordinator.IPFSClient })
+		if !ok {
+			break
+		}
+		c = w.Unwrap()
+	}
+	return nil
+}
+
+// IsLocal 报告 ref 是否为上传的模块，协调器据此避免让 Pod 从网关拉取它。
+func (u *uploadedModules) IsLocal(ref string) bool {
+	_, ok := u.board.module(ref)
+	return ok
+}
+
+// Unwrap 返回下游客户端，协调器据此继续查找其他可选接口。
+func (u *uploadedModules) Unwrap() coordinator.IPFSClient {
+	return u.inner
+}
+
+// requireToken 拒绝在非回环地址上监听且未配置令牌的 API 任务来源。
+func requireToken(addr, token string) error {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("listen address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("listen address %q is not loopback; a token is required", addr)
+}
@@ -0,0 +1,119 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// maxRecentBlocks 是为重组检测保留的最近检查点数量。
+	maxRecentBlocks = 64
+	// dedupeWindow 是已投递 TaskID 的保留区块数，超出窗口的记录会被清理。
+	dedupeWindow = 100_000
+)
+
+// blockRef 记录某个已处理区块的高度与哈希。
+type blockRef struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+// evmCheckpoint 是链上任务来源的进度：最后处理完成的区块、最近若干检查点的区块哈希
+// （重启或轮询时据此发现重组），以及已投递的 TaskID（重扫时去重）。
+type evmCheckpoint struct {
+	Block     uint64            `json:"block"`
+	Recent    []blockRef        `json:"recent,omitempty"`
+	Delivered map[string]uint64 `json:"delivered,omitempty"`
+}
+
+// loadCheckpoint 读取检查点文件，path 为空或文件不存在时返回空检查点。
+func loadCheckpoint(path string) (*evmCheckpoint, error) {
+	cp := &evmCheckpoint{Delivered: map[string]uint64{}}
+	if path == "" {
+		return cp, nil
+	}
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cp, nil
+		}
+		return nil, fmt.Errorf("read checkpoint %s: %w", path, err)
+	}
+	if err := json.Unmarshal(payload, cp); err != nil {
+		return nil, fmt.Errorf("decode checkpoint %s: %w", path, err)
+	}
+	if cp.Delivered == nil {
+		cp.Delivered = map[string]uint64{}
+	}
+	return cp, nil
+}
+
+// save 原子写入检查点，path 为空时只保留在内存中。
+func (cp *evmCheckpoint) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	payload, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("encode checkpoint: %w", err)
+	}
+	return writeFileAtomic(path, payload)
+}
+
+// advance 记录 ref 已处理完成，并清理超出窗口的检查点与 TaskID。
+func (cp *evmCheckpoint) advance(ref blockRef) {
+	cp.Block = ref.Number
+	cp.Recent = append(cp.Recent, ref)
+	if n := len(cp.Recent) - maxRecentBlocks; n > 0 {
+		cp.Recent = append([]blockRef(nil), cp.Recent[n:]...)
+	}
+	if ref.Number > dedupeWindow {
+		for id, block := range cp.Delivered {
+			if block < ref.Number-dedupeWindow {
+				delete(cp.Delivered, id)
+			}
+		}
+	}
+}
+
+// rewind 回退到 block，丢弃其后的检查点；已投递的 TaskID 保留，用于重扫时去重。
+func (cp *evmCheckpoint) rewind(block uint64) {
+	cp.Block = block
+	kept := cp.Recent[:0]
+	for _, ref := range cp.Recent {
+		if ref.Number <= block {
+			kept = append(kept, ref)
+		}
+	}
+	cp.Recent = kept
+}
+
+// writeFileAtomic 先写同目录临时文件并 fsync，再重命名覆盖目标，避免崩溃时留下半截文件。
+func writeFileAtomic(path string, payload []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("commit %s: %w", path, err)
+	}
+	return nil
+}
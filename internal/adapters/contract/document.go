@@ -0,0 +1,53 @@
+package contract
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"executor/internal/coordinator"
+)
+
+// taskDocument 是文件与 HTTP 任务来源接受的 TaskRequest 形式：input_json 可以直接写 JSON 对象，
+// 写成字符串时按 TaskRequest 的默认规则视为 base64。
+type taskDocument struct {
+	coordinator.TaskRequest
+	InputJSON json.RawMessage `json:"input_json,omitempty"`
+}
+
+// request 还原为 TaskRequest。
+func (d taskDocument) request() (coordinator.TaskRequest, error) {
+	task := d.TaskRequest
+	raw := []byte(d.InputJSON)
+	if len(raw) == 0 || string(raw) == "null" {
+		return task, nil
+	}
+	if raw[0] != '"' {
+		task.InputJSON = raw
+		return task, nil
+	}
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return task, fmt.Errorf("decode input_json: %w", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return task, fmt.Errorf("decode input_json: %w", err)
+	}
+	task.InputJSON = decoded
+	return task, nil
+}
+
+// resultRecord 在 TaskResult 之外补充错误文本（TaskResult.Error 不参与序列化）。
+type resultRecord struct {
+	coordinator.TaskResult
+	Error string `json:"error,omitempty"`
+}
+
+func newResultRecord(result coordinator.TaskResult) resultRecord {
+	rec := resultRecord{TaskResult: result}
+	if result.Error != nil {
+		rec.Error = result.Error.Error()
+	}
+	return rec
+}
@@ -0,0 +1,608 @@
+package contract
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"executor/internal/coordinator"
+	"executor/internal/evm"
+)
+
+// 任务合约的 ABI 约定。taskId 为 bytes32，在协调器内以 0x 前缀的十六进制字符串作为 TaskID。
+const (
+	// TaskSubmitted(bytes32 indexed taskId, string wasmCid, string inputCid, string dataCid, string entry)
+	taskSubmittedEvent = "TaskSubmitted(bytes32,string,string,string,string)"
+	// ackTask(bytes32 taskId)
+	ackTaskMethod = "ackTask(bytes32)"
+	// publishResult(bytes32 taskId, bool success, string resultCid, string output)
+	publishResultMethod = "publishResult(bytes32,bool,string,string)"
+)
+
+var taskSubmittedTypes = []string{"string", "string", "string", "string"}
+
+const (
+	defaultEVMPollInterval = 5 * time.Second
+	defaultEVMBlockRange   = 2000
+	defaultEVMTimeout      = 30 * time.Second
+	defaultReceiptTimeout  = 5 * time.Minute
+	// maxResultOutput 限制写入链上的输出长度（含截断标记），完整结果通过 ResultCID 获取。
+	maxResultOutput = 1024
+	// 被截断的链上输出以这些标记结尾。
+	truncatedMarker    = "...[truncated]"
+	truncatedMarkerCID = "...[truncated; full result at resultCid]"
+)
+
+// EVMConfig 描述 EVM 合约适配器的连接与签名参数。
+type EVMConfig struct {
+	// RPCURL 是节点的 JSON-RPC HTTP 地址。
+	RPCURL string
+	// Contract 是任务合约地址。
+	Contract string
+	// PrivateKey 是发送 ackTask/publishResult 交易的账户私钥（十六进制）。
+	PrivateKey string
+	// ChainID 为 0 时在首次发送交易前通过 eth_chainId 查询。
+	ChainID uint64
+	// FromBlock 是开始扫描的区块，为 0 时从当前已确认的最新区块开始；检查点存在时以检查点为准。
+	FromBlock uint64
+	// Confirmations 是事件所在区块之上需要的确认数，只有达到确认深度的任务才会投递。
+	// 应大于预期的最大重组深度：已投递的任务无法撤回。
+	Confirmations uint64
+	// CheckpointPath 非空时持久化扫描进度、近期区块哈希与已投递的 TaskID，重启后从断点继续。
+	CheckpointPath string
+	// PollInterval 是追上链头后轮询新区块的间隔。
+	PollInterval time.Duration
+	// BlockRange 是单次 eth_getLogs 查询的最大区块数。
+	BlockRange uint64
+	// GasLimit 为 0 时通过 eth_estimateGas 估算（上浮 20%）。
+	GasLimit uint64
+	// ReceiptTimeout 是上一笔交易等待上链的时长，超时仍无回执时按节点的 pending 计数重新同步 nonce。
+	ReceiptTimeout time.Duration
+}
+
+func (c *EVMConfig) applyDefaults() {
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultEVMPollInterval
+	}
+	if c.BlockRange == 0 {
+		c.BlockRange = defaultEVMBlockRange
+	}
+	if c.ReceiptTimeout <= 0 {
+		c.ReceiptTimeout = defaultReceiptTimeout
+	}
+}
+
+// EVMClient 通过 eth_getLogs 轮询任务合约的 TaskSubmitted 事件，并以 EIP-155 签名交易调用
+// ackTask/publishResult。
+type EVMClient struct {
+	cfg      EVMConfig
+	rpc      *rpcClient
+	contract evm.Address
+	key      *evm.PrivateKey
+	from     evm.Address
+	topic    string
+	log      coordinator.Logger
+
+	// txMu 串行化交易发送，保证 nonce 连续。
+	txMu      sync.Mutex
+	chainID   *big.Int
+	nextNonce uint64
+	// lastTx 与 lastSentAt 记录上一笔交易，用于发现被丢弃或长期未上链的交易。
+	lastTx     string
+	lastSentAt time.Time
+}
+
+// NewEVMClient 校验配置并构造适配器，不会访问网络。
+func NewEVMClient(cfg EVMConfig, log coordinator.Logger) (*EVMClient, error) {
+	cfg.applyDefaults()
+	url := strings.TrimSpace(cfg.RPCURL)
+	if url == "" {
+		return nil, errors.New("evm rpc url is empty")
+	}
+	contract, err := evm.ParseAddress(cfg.Contract)
+	if err != nil {
+		return nil, fmt.Errorf("evm contract: %w", err)
+	}
+	key, err := evm.ParsePrivateKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("evm signer: %w", err)
+	}
+	topic := evm.EventTopic(taskSubmittedEvent)
+	c := &EVMClient{
+		cfg:      cfg,
+		rpc:      &rpcClient{url: url, client: &http.Client{Timeout: defaultEVMTimeout}},
+		contract: contract,
+		key:      key,
+		from:     key.Address(),
+		topic:    "0x" + hex.EncodeToString(topic[:]),
+		log:      log,
+	}
+	if cfg.ChainID != 0 {
+		c.chainID = new(big.Int).SetUint64(cfg.ChainID)
+	}
+	return c, nil
+}
+
+// Address 返回签名账户地址。
+func (c *EVMClient) Address() string {
+	return c.from.Hex()
+}
+
+// evmLog 是 eth_getLogs 返回的一条日志。
+type evmLog struct {
+	Address         string   `json:"address"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	BlockNumber     string   `json:"blockNumber"`
+	BlockHash       string   `json:"blockHash"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        string   `json:"logIndex"`
+	Removed         bool     `json:"removed"`
+}
+
+// SubscribeTasks 按 BlockRange 分段扫描已达到确认深度的区块，追上后每 PollInterval 轮询一次。
+// 每段处理完成后写入检查点；发现检查点区块的哈希变化（重组）时回退到最近的共同祖先重扫，
+// 已投递的 TaskID 不会重复投递。节点暂时不可用时记录警告并在下个周期重试，直到上下文取消。
+func (c *EVMClient) SubscribeTasks(ctx context.Context, out chan<- coordinator.TaskRequest) error {
+	cp, err := loadCheckpoint(c.cfg.CheckpointPath)
+	if err != nil {
+		return err
+	}
+	next, started := c.cfg.FromBlock, c.cfg.FromBlock != 0
+	if cp.Block != 0 {
+		next, started = cp.Block+1, true
+		c.log.Infof("evm: resuming from checkpoint block %d", cp.Block)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		head, err := c.blockNumber(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.log.Warnf("evm: query head: %v", err)
+			if err := sleepCtx(ctx, c.cfg.PollInterval); err != nil {
+				return err
+			}
+			continue
+		}
+		if head < c.cfg.Confirmations {
+			if err := sleepCtx(ctx, c.cfg.PollInterval); err != nil {
+				return err
+			}
+			continue
+		}
+		safe := head - c.cfg.Confirmations
+		if !started {
+			next, started = safe, true
+			c.log.Infof("evm: watching %s for %s from block %d", c.contract.Hex(), taskSubmittedEvent, next)
+		}
+
+		rewound, err := c.checkReorg(ctx, cp)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.log.Warnf("evm: check reorg: %v", err)
+			if err := sleepCtx(ctx, c.cfg.PollInterval); err != nil {
+				return err
+			}
+			continue
+		}
+		if rewound {
+			next = cp.Block + 1
+			c.saveCheckpoint(cp)
+		}
+		if next > safe {
+			if err := sleepCtx(ctx, c.cfg.PollInterval); err != nil {
+				return err
+			}
+			continue
+		}
+
+		to := min(safe, next+c.cfg.BlockRange-1)
+		ref, err := c.scanRange(ctx, cp, out, next, to)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.log.Warnf("evm: scan blocks %d-%d: %v", next, to, err)
+			if err := sleepCtx(ctx, c.cfg.PollInterval); err != nil {
+				return err
+			}
+			continue
+		}
+		cp.advance(ref)
+		c.saveCheckpoint(cp)
+		next = to + 1
+		if to == safe {
+			if err := sleepCtx(ctx, c.cfg.PollInterval); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// scanRange 读取 [from, to] 的任务事件并投递，返回 to 区块的引用。
+// 日志所在区块的哈希与当前链不一致时说明扫描期间发生了重组，整段放弃由下一轮重试。
+func (c *EVMClient) scanRange(ctx context.Context, cp *evmCheckpoint, out chan<- coordinator.TaskRequest, from, to uint64) (blockRef, error) {
+	toHash, err := c.blockHash(ctx, to)
+	if err != nil {
+		return blockRef{}, err
+	}
+	logs, err := c.getLogs(ctx, from, to)
+	if err != nil {
+		return blockRef{}, err
+	}
+
+	hashes := map[uint64]string{to: toHash}
+	var tasks []coordinator.TaskRequest
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		block, err := parseQuantity(l.BlockNumber)
+		if err != nil {
+			return blockRef{}, err
+		}
+		hash, ok := hashes[block]
+		if !ok {
+			if hash, err = c.blockHash(ctx, block); err != nil {
+				return blockRef{}, err
+			}
+			hashes[block] = hash
+		}
+		if !strings.EqualFold(hash, l.BlockHash) {
+			return blockRef{}, fmt.Errorf("block %d changed while scanning (log in %s, chain has %s)", block, l.BlockHash, hash)
+		}
+		task, err := decodeTaskLog(l)
+		if err != nil {
+			c.log.Warnf("evm: skip log %s#%s: %v", l.TransactionHash, l.LogIndex, err)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	for _, task := range tasks {
+		if block, seen := cp.Delivered[task.TaskID]; seen {
+			c.log.Infof("evm: task %s already delivered from block %d, skipping", task.TaskID, block)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return blockRef{}, ctx.Err()
+		case out <- task:
+		}
+		block, _ := strconv.ParseUint(task.ResultMetadata["evm.block"], 10, 64)
+		cp.Delivered[task.TaskID] = block
+		// 每投递一个任务就落盘，崩溃重启后不会重复投递。
+		c.saveCheckpoint(cp)
+		c.log.Infof("evm: task %s submitted in block %d", task.TaskID, block)
+	}
+	return blockRef{Number: to, Hash: toHash}, nil
+}
+
+// checkReorg 从新到旧核对检查点区块的哈希，找到仍在链上的最近检查点并回退到该处。
+// 所有检查点都已失效时回退到最早检查点之前 BlockRange 个区块。
+func (c *EVMClient) checkReorg(ctx context.Context, cp *evmCheckpoint) (bool, error) {
+	for i := len(cp.Recent) - 1; i >= 0; i-- {
+		ref := cp.Recent[i]
+		hash, err := c.blockHash(ctx, ref.Number)
+		if err != nil {
+			return false, err
+		}
+		if strings.EqualFold(hash, ref.Hash) {
+			if i == len(cp.Recent)-1 {
+				return false, nil
+			}
+			c.log.Warnf("evm: reorg detected after block %d, rewinding from %d", ref.Number, cp.Block)
+			cp.rewind(ref.Number)
+			return true, nil
+		}
+	}
+	if len(cp.Recent) == 0 {
+		return false, nil
+	}
+	oldest := cp.Recent[0].Number
+	target := oldest - min(oldest, c.cfg.BlockRange)
+	c.log.Warnf("evm: reorg deeper than %d checkpoints, rewinding from %d to %d", len(cp.Recent), cp.Block, target)
+	cp.rewind(target)
+	return true, nil
+}
+
+func (c *EVMClient) saveCheckpoint(cp *evmCheckpoint) {
+	if err := cp.save(c.cfg.CheckpointPath); err != nil {
+		c.log.Warnf("evm: save checkpoint: %v", err)
+	}
+}
+
+// AckTask 发送 ackTask(taskId) 交易，不等待上链。
+func (c *EVMClient) AckTask(ctx context.Context, taskID string) error {
+	id, err := parseTaskID(taskID)
+	if err != nil {
+		return err
+	}
+	data, err := evm.EncodeCall(ackTaskMethod, id)
+	if err != nil {
+		return err
+	}
+	hash, err := c.sendTransaction(ctx, data)
+	if err != nil {
+		return fmt.Errorf("ack task %s: %w", taskID, err)
+	}
+	c.log.Infof("evm: ack task %s in tx %s", taskID, hash)
+	return nil
+}
+
+// PublishResult 发送 publishResult 交易；成功时 output 为执行器输出，失败时为失败原因与错误，超长会截断。
+func (c *EVMClient) PublishResult(ctx context.Context, result coordinator.TaskResult) error {
+	id, err := parseTaskID(result.TaskID)
+	if err != nil {
+		return err
+	}
+	output := result.OutputValue
+	if !result.Success {
+		output = string(result.FailureReason)
+		if result.Error != nil {
+			output = fmt.Sprintf("%s: %v", result.FailureReason, result.Error)
+		}
+	}
+	output = truncateOutput(output, result.ResultCID != "")
+	data, err := evm.EncodeCall(publishResultMethod, id, result.Success, result.ResultCID, output)
+	if err != nil {
+		return err
+	}
+	hash, err := c.sendTransaction(ctx, data)
+	if err != nil {
+		return fmt.Errorf("publish result %s: %w", result.TaskID, err)
+	}
+	c.log.Infof("evm: published result of task %s (success=%t) in tx %s", result.TaskID, result.Success, hash)
+	return nil
+}
+
+// truncateOutput 把超过 maxResultOutput 的输出截断到完整的 UTF-8 字符边界，并追加截断标记；
+// 有 resultCid 时标记指向它，链上读者据此知道值不完整且不能当作 JSON 解析。
+func truncateOutput(output string, hasResultCID bool) string {
+	if len(output) <= maxResultOutput {
+		return output
+	}
+	marker := truncatedMarker
+	if hasResultCID {
+		marker = truncatedMarkerCID
+	}
+	cut := maxResultOutput - len(marker)
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return output[:cut] + marker
+}
+
+// sendTransaction 签名并广播一笔调用任务合约的交易，返回交易哈希。
+// nonce 取节点 pending 计数与本地记录的较大者，避免连续发送时复用 nonce；发送失败、
+// 上一笔交易已被节点丢弃或超过 ReceiptTimeout 仍未上链时，改以节点的 pending 计数为准，
+// 避免之后的交易都卡在 nonce 空洞之后。
+func (c *EVMClient) sendTransaction(ctx context.Context, data []byte) (string, error) {
+	c.txMu.Lock()
+	defer c.txMu.Unlock()
+
+	if c.chainID == nil {
+		var raw string
+		if err := c.rpc.call(ctx, &raw, "eth_chainId"); err != nil {
+			return "", err
+		}
+		id, err := parseBig(raw)
+		if err != nil {
+			return "", fmt.Errorf("eth_chainId: %w", err)
+		}
+		c.chainID = id
+	}
+
+	var raw string
+	if err := c.rpc.call(ctx, &raw, "eth_getTransactionCount", c.from.Hex(), "pending"); err != nil {
+		return "", err
+	}
+	nonce, err := parseQuantity(raw)
+	if err != nil {
+		return "", fmt.Errorf("eth_getTransactionCount: %w", err)
+	}
+	if nonce < c.nextNonce && c.lastTxStalled(ctx) {
+		c.log.Warnf("evm: transaction %s not mined, resyncing nonce from %d to %d", c.lastTx, c.nextNonce, nonce)
+		c.nextNonce = nonce
+	}
+	nonce = max(nonce, c.nextNonce)
+
+	if err := c.rpc.call(ctx, &raw, "eth_gasPrice"); err != nil {
+		return "", err
+	}
+	gasPrice, err := parseBig(raw)
+	if err != nil {
+		return "", fmt.Errorf("eth_gasPrice: %w", err)
+	}
+
+	gas := c.cfg.GasLimit
+	if gas == 0 {
+		call := map[string]string{
+			"from": c.from.Hex(),
+			"to":   c.contract.Hex(),
+			"data": "0x" + hex.EncodeToString(data),
+		}
+		if err := c.rpc.call(ctx, &raw, "eth_estimateGas", call); err != nil {
+			return "", err
+		}
+		estimate, err := parseQuantity(raw)
+		if err != nil {
+			return "", fmt.Errorf("eth_estimateGas: %w", err)
+		}
+		gas = estimate + estimate/5
+	}
+
+	to := c.contract
+	signed, hash, err := evm.SignLegacyTx(evm.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gas,
+		To:       &to,
+		Value:    new(big.Int),
+		Data:     data,
+	}, c.chainID, c.key)
+	if err != nil {
+		return "", fmt.Errorf("sign transaction: %w", err)
+	}
+	if err := c.rpc.call(ctx, &raw, "eth_sendRawTransaction", "0x"+hex.EncodeToString(signed)); err != nil {
+		// 交易是否进入交易池无法确定，下次发送以节点的 pending 计数为准。
+		c.nextNonce = 0
+		return "", err
+	}
+	c.nextNonce = nonce + 1
+	c.lastTx = "0x" + hex.EncodeToString(hash)
+	c.lastSentAt = time.Now()
+	return c.lastTx, nil
+}
+
+// lastTxStalled 判断上一笔交易是否已不可能按本地 nonce 上链：节点已不认识该交易（被丢弃或替换），
+// 或超过 ReceiptTimeout 仍没有回执。查询失败时保守地认为交易仍有效。
+func (c *EVMClient) lastTxStalled(ctx context.Context) bool {
+	if c.lastTx == "" {
+		return true
+	}
+	var receipt json.RawMessage
+	if err := c.rpc.call(ctx, &receipt, "eth_getTransactionReceipt", c.lastTx); err != nil {
+		return false
+	}
+	if !isNull(receipt) {
+		return false
+	}
+	if time.Since(c.lastSentAt) > c.cfg.ReceiptTimeout {
+		return true
+	}
+	var tx json.RawMessage
+	if err := c.rpc.call(ctx, &tx, "eth_getTransactionByHash", c.lastTx); err != nil {
+		return false
+	}
+	return isNull(tx)
+}
+
+func (c *EVMClient) blockNumber(ctx context.Context) (uint64, error) {
+	var raw string
+	if err := c.rpc.call(ctx, &raw, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return parseQuantity(raw)
+}
+
+// blockHash 通过 eth_getBlockByNumber 查询区块哈希。
+func (c *EVMClient) blockHash(ctx context.Context, number uint64) (string, error) {
+	var block *struct {
+		Hash string `json:"hash"`
+	}
+	if err := c.rpc.call(ctx, &block, "eth_getBlockByNumber", quantity(number), false); err != nil {
+		return "", err
+	}
+	if block == nil || block.Hash == "" {
+		return "", fmt.Errorf("block %d not found", number)
+	}
+	return block.Hash, nil
+}
+
+func (c *EVMClient) getLogs(ctx context.Context, from, to uint64) ([]evmLog, error) {
+	filter := map[string]any{
+		"fromBlock": quantity(from),
+		"toBlock":   quantity(to),
+		"address":   c.contract.Hex(),
+		"topics":    []string{c.topic},
+	}
+	var logs []evmLog
+	if err := c.rpc.call(ctx, &logs, "eth_getLogs", filter); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// decodeTaskLog 把 TaskSubmitted 日志解码为任务请求，链上位置写入 ResultMetadata。
+func decodeTaskLog(l evmLog) (coordinator.TaskRequest, error) {
+	if len(l.Topics) != 2 {
+		return coordinator.TaskRequest{}, fmt.Errorf("expected 2 topics, got %d", len(l.Topics))
+	}
+	id, err := parseTaskID(l.Topics[1])
+	if err != nil {
+		return coordinator.TaskRequest{}, err
+	}
+	data, err := parseData(l.Data)
+	if err != nil {
+		return coordinator.TaskRequest{}, err
+	}
+	args, err := evm.DecodeArgs(taskSubmittedTypes, data)
+	if err != nil {
+		return coordinator.TaskRequest{}, err
+	}
+	block, err := parseQuantity(l.BlockNumber)
+	if err != nil {
+		return coordinator.TaskRequest{}, err
+	}
+	task := coordinator.TaskRequest{
+		TaskID:   formatTaskID(id),
+		WasmCID:  args[0].(string),
+		InputCID: args[1].(string),
+		DataCID:  args[2].(string),
+		Entry:    args[3].(string),
+		ResultMetadata: map[string]string{
+			"evm.block": strconv.FormatUint(block, 10),
+			"evm.tx":    l.TransactionHash,
+		},
+	}
+	if task.WasmCID == "" {
+		return coordinator.TaskRequest{}, errors.New("empty wasm cid")
+	}
+	return task, nil
+}
+
+// parseTaskID 把 0x 前缀的 64 位十六进制 TaskID 还原为 bytes32。
+func parseTaskID(s string) ([32]byte, error) {
+	var id [32]byte
+	raw, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(raw) != len(id) {
+		return id, fmt.Errorf("task id %q is not a bytes32", s)
+	}
+	copy(id[:], raw)
+	return id, nil
+}
+
+func formatTaskID(id [32]byte) string {
+	return "0x" + hex.EncodeToString(id[:])
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func parseBig(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok || !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("invalid quantity %q", s)
+	}
+	return n, nil
+}
+
+// sleepCtx 等待 d 或上下文取消。
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
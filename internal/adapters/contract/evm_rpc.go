@@ -0,0 +1,123 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"executor/internal/coordinator"
+)
+
+// maxRPCResponse 限制单次 JSON-RPC 响应大小，eth_getLogs 的区块范围由 BlockRange 控制。
+const maxRPCResponse = 32 << 20
+
+// rpcClient 是最小的以太坊 JSON-RPC over HTTP 客户端。
+type rpcClient struct {
+	url    string
+	client *http.Client
+	nextID atomic.Uint64
+}
+
+// rpcError 是 JSON-RPC 响应中的 error 对象。
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// call 调用 method 并把 result 解码到 out；HTTP 429/5xx 标记为暂时性错误。
+func (r *rpcClient) call(ctx context.Context, out any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      r.nextID.Add(1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("encode %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("%s: %s: %s", method, resp.Status, strings.TrimSpace(string(payload)))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return coordinator.Transient(err)
+		}
+		return err
+	}
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, maxRPCResponse+1))
+	if err != nil {
+		return fmt.Errorf("read %s response: %w", method, err)
+	}
+	if len(payload) > maxRPCResponse {
+		return fmt.Errorf("%s response larger than %d bytes", method, maxRPCResponse)
+	}
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+	if envelope.Error != nil {
+		return fmt.Errorf("%s: %w", method, envelope.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Result, out); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// quantity 编码 JSON-RPC 的十六进制整数。
+func quantity(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
+
+// parseQuantity 解析 "0x1a" 形式的十六进制整数。
+func parseQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	n, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return n, nil
+}
+
+// parseData 解析 "0x..." 形式的字节数据。
+func parseData(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data: %w", err)
+	}
+	return b, nil
+}
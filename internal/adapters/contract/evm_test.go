@@ -0,0 +1,436 @@
+package contract
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"executor/internal/coordinator"
+	"executor/internal/evm"
+)
+
+const (
+	testContract = "0x00000000000000000000000000000000000000aa"
+	testKey      = "0x4646464646464646464646464646464646464646464646464646464646464646"
+)
+
+// testLogger 把适配器日志写入测试输出。
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Infof(format string, args ...any)  { l.t.Logf("[INFO] "+format, args...) }
+func (l testLogger) Warnf(format string, args ...any)  { l.t.Logf("[WARN] "+format, args...) }
+func (l testLogger) Errorf(format string, args ...any) { l.t.Logf("[ERROR] "+format, args...) }
+
+// fakeChain 是 JSON-RPC 节点的替身：区块哈希与日志可在测试中修改以模拟重组，
+// 交易只记录不执行。
+type fakeChain struct {
+	t  *testing.T
+	mu sync.Mutex
+
+	head    uint64
+	hashes  map[uint64]string
+	logs    []evmLog
+	queries [][2]uint64 // eth_getLogs 的 [from, to]
+
+	pending uint64            // eth_getTransactionCount(pending)
+	known   map[string]bool   // 节点交易池或链上已知的交易
+	sent    []string          // eth_sendRawTransaction 收到的原始交易
+	failing map[string]string // method -> 错误信息
+}
+
+func newFakeChain(t *testing.T, head uint64) *fakeChain {
+	f := &fakeChain{t: t, head: head, hashes: map[uint64]string{}, known: map[string]bool{}, failing: map[string]string{}}
+	for n := uint64(0); n <= head; n++ {
+		f.hashes[n] = fmt.Sprintf("0x%064x", n)
+	}
+	return f
+}
+
+// addTask 在 block 中加入一条 TaskSubmitted 日志。
+func (f *fakeChain) addTask(block uint64, id byte, wasmCID, entry string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var taskID [32]byte
+	taskID[31] = id
+	data, err := evm.EncodeArgs(wasmCID, "input-"+wasmCID, "", entry)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	topic := evm.EventTopic(taskSubmittedEvent)
+	f.logs = append(f.logs, evmLog{
+		Address:         testContract,
+		Topics:          []string{"0x" + hex.EncodeToString(topic[:]), formatTaskID(taskID)},
+		Data:            "0x" + hex.EncodeToString(data),
+		BlockNumber:     quantity(block),
+		BlockHash:       f.hashes[block],
+		TransactionHash: fmt.Sprintf("0x%064x", 0x1000+int(id)),
+		LogIndex:        "0x0",
+	})
+}
+
+// reorg 替换 from 及之后所有区块的哈希，并丢弃这些区块中的日志。
+func (f *fakeChain) reorg(from uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for n := from; n <= f.head; n++ {
+		f.hashes[n] = fmt.Sprintf("0x%064x", 0xf000+n)
+	}
+	kept := f.logs[:0]
+	for _, l := range f.logs {
+		if block, _ := parseQuantity(l.BlockNumber); block < from {
+			kept = append(kept, l)
+		}
+	}
+	f.logs = kept
+}
+
+func (f *fakeChain) mine(head uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for n := f.head + 1; n <= head; n++ {
+		f.hashes[n] = fmt.Sprintf("0x%064x", n)
+	}
+	f.head = head
+}
+
+func (f *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reply := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if msg, ok := f.failing[req.Method]; ok {
+		reply["error"] = rpcError{Code: -32000, Message: msg}
+		json.NewEncoder(w).Encode(reply)
+		return
+	}
+	param := func(i int, v any) {
+		if err := json.Unmarshal(req.Params[i], v); err != nil {
+			f.t.Errorf("%s param %d: %v", req.Method, i, err)
+		}
+	}
+	switch req.Method {
+	case "eth_blockNumber":
+		reply["result"] = quantity(f.head)
+	case "eth_getBlockByNumber":
+		var tag string
+		param(0, &tag)
+		n, _ := parseQuantity(tag)
+		if hash, ok := f.hashes[n]; ok && n <= f.head {
+			reply["result"] = map[string]string{"hash": hash}
+		} else {
+			reply["result"] = nil
+		}
+	case "eth_getLogs":
+		var filter struct {
+			FromBlock string   `json:"fromBlock"`
+			ToBlock   string   `json:"toBlock"`
+			Address   string   `json:"address"`
+			Topics    []string `json:"topics"`
+		}
+		param(0, &filter)
+		from, _ := parseQuantity(filter.FromBlock)
+		to, _ := parseQuantity(filter.ToBlock)
+		f.queries = append(f.queries, [2]uint64{from, to})
+		logs := []evmLog{}
+		for _, l := range f.logs {
+			block, _ := parseQuantity(l.BlockNumber)
+			if block >= from && block <= to && strings.EqualFold(l.Address, filter.Address) && l.Topics[0] == filter.Topics[0] {
+				logs = append(logs, l)
+			}
+		}
+		reply["result"] = logs
+	case "eth_chainId":
+		reply["result"] = "0x1"
+	case "eth_getTransactionCount":
+		reply["result"] = quantity(f.pending)
+	case "eth_gasPrice":
+		reply["result"] = "0x3b9aca00"
+	case "eth_estimateGas":
+		reply["result"] = "0x5208"
+	case "eth_sendRawTransaction":
+		var raw string
+		param(0, &raw)
+		payload, _ := parseData(raw)
+		hash := "0x" + hex.EncodeToString(evm.Keccak256(payload))
+		f.sent = append(f.sent, raw)
+		f.known[hash] = true
+		reply["result"] = hash
+	case "eth_getTransactionReceipt":
+		reply["result"] = nil
+	case "eth_getTransactionByHash":
+		var hash string
+		param(0, &hash)
+		if f.known[hash] {
+			reply["result"] = map[string]string{"hash": hash}
+		} else {
+			reply["result"] = nil
+		}
+	default:
+		reply["error"] = rpcError{Code: -32601, Message: "method not found"}
+	}
+	json.NewEncoder(w).Encode(reply)
+}
+
+func newTestEVMClient(t *testing.T, chain *fakeChain, cfg EVMConfig) *EVMClient {
+	t.Helper()
+	srv := httptest.NewServer(chain)
+	t.Cleanup(srv.Close)
+	cfg.RPCURL = srv.URL
+	cfg.Contract = testContract
+	cfg.PrivateKey = testKey
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = 10 * time.Millisecond
+	}
+	c, err := NewEVMClient(cfg, testLogger{t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// subscribe 在后台运行 SubscribeTasks，测试结束时停止。
+func subscribe(t *testing.T, c *EVMClient) <-chan coordinator.TaskRequest {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan coordinator.TaskRequest, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.SubscribeTasks(ctx, out)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return out
+}
+
+func receiveTask(t *testing.T, tasks <-chan coordinator.TaskRequest) coordinator.TaskRequest {
+	t.Helper()
+	select {
+	case task := <-tasks:
+		return task
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for task")
+		return coordinator.TaskRequest{}
+	}
+}
+
+func expectNoTask(t *testing.T, tasks <-chan coordinator.TaskRequest, wait time.Duration) {
+	t.Helper()
+	select {
+	case task := <-tasks:
+		t.Fatalf("unexpected task %s", task.TaskID)
+	case <-time.After(wait):
+	}
+}
+
+func TestEVMClientDecodesTaskLogs(t *testing.T) {
+	chain := newFakeChain(t, 10)
+	chain.addTask(4, 1, "bafy-module", "run")
+	c := newTestEVMClient(t, chain, EVMConfig{FromBlock: 1, Confirmations: 2})
+	tasks := subscribe(t, c)
+
+	task := receiveTask(t, tasks)
+	if want := "0x" + strings.Repeat("0", 62) + "01"; task.TaskID != want {
+		t.Errorf("TaskID = %s, want %s", task.TaskID, want)
+	}
+	if task.WasmCID != "bafy-module" || task.InputCID != "input-bafy-module" || task.DataCID != "" || task.Entry != "run" {
+		t.Errorf("unexpected task fields: %+v", task)
+	}
+	if task.ResultMetadata["evm.block"] != "4" || task.ResultMetadata["evm.tx"] == "" {
+		t.Errorf("unexpected metadata: %v", task.ResultMetadata)
+	}
+
+	// 未达到确认深度的任务不会投递。
+	chain.addTask(9, 2, "bafy-late", "")
+	expectNoTask(t, tasks, 100*time.Millisecond)
+	chain.mine(11)
+	if task := receiveTask(t, tasks); task.WasmCID != "bafy-late" {
+		t.Errorf("got %s, want the task from block 9", task.WasmCID)
+	}
+}
+
+func TestEVMClientRollsBackOnReorg(t *testing.T) {
+	chain := newFakeChain(t, 6)
+	chain.addTask(5, 1, "bafy-a", "")
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	c := newTestEVMClient(t, chain, EVMConfig{FromBlock: 1, BlockRange: 2, CheckpointPath: checkpoint})
+	tasks := subscribe(t, c)
+
+	if task := receiveTask(t, tasks); task.WasmCID != "bafy-a" {
+		t.Fatalf("got %s, want bafy-a", task.WasmCID)
+	}
+	waitFor(t, func() bool {
+		cp, err := loadCheckpoint(checkpoint)
+		return err == nil && cp.Block == 6
+	})
+
+	// 区块 5 起被替换：任务 a 被重新打包进区块 6，同时新增任务 b。
+	chain.mu.Lock()
+	chain.queries = nil
+	chain.mu.Unlock()
+	chain.reorg(5)
+	chain.addTask(6, 1, "bafy-a", "")
+	chain.addTask(6, 2, "bafy-b", "")
+
+	if task := receiveTask(t, tasks); task.WasmCID != "bafy-b" {
+		t.Fatalf("got %s, want bafy-b", task.WasmCID)
+	}
+	expectNoTask(t, tasks, 100*time.Millisecond)
+
+	chain.mu.Lock()
+	rescanned := len(chain.queries) > 0 && chain.queries[0][0] == 5
+	queries := chain.queries
+	chain.mu.Unlock()
+	if !rescanned {
+		t.Fatalf("expected rescan from the common ancestor (block 4), got queries %v", queries)
+	}
+	cp, err := loadCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.mu.Lock()
+	head := chain.hashes[6]
+	chain.mu.Unlock()
+	if got := cp.Recent[len(cp.Recent)-1]; got.Number != 6 || got.Hash != head {
+		t.Errorf("checkpoint head = %+v, want block 6 on the new chain", got)
+	}
+}
+
+func TestEVMClientResyncsNonce(t *testing.T) {
+	chain := newFakeChain(t, 1)
+	chain.pending = 3
+	c := newTestEVMClient(t, chain, EVMConfig{ChainID: 1})
+	ctx := context.Background()
+	taskID := formatTaskID([32]byte{31: 7})
+
+	nonces := func() []uint64 {
+		chain.mu.Lock()
+		defer chain.mu.Unlock()
+		var out []uint64
+		for _, raw := range chain.sent {
+			out = append(out, sentNonce(t, c, raw))
+		}
+		return out
+	}
+
+	if err := c.AckTask(ctx, taskID); err != nil {
+		t.Fatal(err)
+	}
+	// 节点尚未把交易计入 pending，本地 nonce 继续递增。
+	if err := c.AckTask(ctx, taskID); err != nil {
+		t.Fatal(err)
+	}
+	if got := nonces(); fmt.Sprint(got) != "[3 4]" {
+		t.Fatalf("nonces = %v, want [3 4]", got)
+	}
+
+	// 上一笔交易被节点丢弃后回到节点的 pending 计数。
+	chain.mu.Lock()
+	chain.known = map[string]bool{}
+	chain.mu.Unlock()
+	if err := c.AckTask(ctx, taskID); err != nil {
+		t.Fatal(err)
+	}
+	if got := nonces(); fmt.Sprint(got) != "[3 4 3]" {
+		t.Fatalf("nonces = %v, want [3 4 3]", got)
+	}
+
+	// 发送失败后同样以节点的 pending 计数为准。
+	chain.mu.Lock()
+	chain.failing["eth_sendRawTransaction"] = "nonce too low"
+	chain.mu.Unlock()
+	if err := c.AckTask(ctx, taskID); err == nil {
+		t.Fatal("expected send error")
+	}
+	chain.mu.Lock()
+	delete(chain.failing, "eth_sendRawTransaction")
+	chain.pending = 4
+	chain.mu.Unlock()
+	if err := c.AckTask(ctx, taskID); err != nil {
+		t.Fatal(err)
+	}
+	if got := nonces(); fmt.Sprint(got) != "[3 4 3 4]" {
+		t.Fatalf("nonces = %v, want [3 4 3 4]", got)
+	}
+}
+
+// sentNonce 按候选 nonce 重新签名，找出原始交易使用的 nonce（签名是确定性的）。
+func sentNonce(t *testing.T, c *EVMClient, raw string) uint64 {
+	t.Helper()
+	taskID := [32]byte{31: 7}
+	data, err := evm.EncodeCall(ackTaskMethod, taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := c.contract
+	for nonce := uint64(0); nonce < 16; nonce++ {
+		signed, _, err := evm.SignLegacyTx(evm.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: big.NewInt(1_000_000_000),
+			Gas:      21000 + 21000/5,
+			To:       &to,
+			Value:    new(big.Int),
+			Data:     data,
+		}, big.NewInt(1), c.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if "0x"+hex.EncodeToString(signed) == raw {
+			return nonce
+		}
+	}
+	t.Fatalf("transaction %s does not match any nonce", raw)
+	return 0
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTruncateOutputKeepsRunes(t *testing.T) {
+	if got := truncateOutput("short", true); got != "short" {
+		t.Errorf("short output changed to %q", got)
+	}
+	// 每个 "é" 占 2 字节，截断点落在字符中间时退回到字符起点。
+	long := `{"text":"` + strings.Repeat("é", maxResultOutput) + `"}`
+	for _, hasCID := range []bool{false, true} {
+		got := truncateOutput(long, hasCID)
+		marker := truncatedMarker
+		if hasCID {
+			marker = truncatedMarkerCID
+		}
+		if len(got) > maxResultOutput || !strings.HasSuffix(got, marker) {
+			t.Errorf("hasCID=%t: len=%d suffix=%q", hasCID, len(got), got[max(0, len(got)-len(marker)):])
+		}
+		if !utf8.ValidString(got) || !strings.HasPrefix(long, strings.TrimSuffix(got, marker)) {
+			t.Errorf("hasCID=%t: truncated output is not a valid prefix", hasCID)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package contract
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"executor/api/taskpb"
+	"executor/internal/coordinator"
+)
+
+const defaultGRPCAddr = ":9090"
+
+// GRPCConfig 描述 gRPC 任务服务（executor.task.v1.TaskService）。
+type GRPCConfig struct {
+	// Addr 是监听地址。
+	Addr string
+	// Token 非空时要求请求携带 authorization: Bearer <Token> 元数据；监听非回环地址时必须设置。
+	Token string
+	// QueueSize 是等待协调器领取的任务上限，队列满时 SubmitTask 返回 RESOURCE_EXHAUSTED。
+	QueueSize int
+	// MaxUploadBytes 是单条请求消息（含上传模块）的大小上限。
+	MaxUploadBytes int64
+	// ResultTTL 是已结束任务的状态与结果的保留时长。
+	ResultTTL time.Duration
+}
+
+func (c *GRPCConfig) applyDefaults() {
+	if c.Addr == "" {
+		c.Addr = defaultGRPCAddr
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultHTTPQueueSize
+	}
+	if c.MaxUploadBytes <= 0 {
+		c.MaxUploadBytes = defaultMaxUploadBytes
+	}
+	if c.ResultTTL <= 0 {
+		c.ResultTTL = defaultResultTTL
+	}
+}
+
+// GRPCClient 是以 gRPC TaskService 为任务来源的 ContractClient，接口定义见 api/taskpb/task.proto：
+// SubmitTask 提交任务（可附带模块），GetTask 查询状态，CancelTask 取消任务（运行中的任务由协调器中止），
+// WatchResults 以服务端流推送结束的任务。与 HTTPClient 一样，状态只保存在内存中。
+type GRPCClient struct {
+	cfg   GRPCConfig
+	log   coordinator.Logger
+	board *taskBoard
+}
+
+// taskService 实现 taskpb.TaskServiceServer。
+type taskService struct {
+	taskpb.UnimplementedTaskServiceServer
+	board *taskBoard
+}
+
+// NewGRPCClient 构造 gRPC 任务来源；服务在 SubscribeTasks 中启动。
+func NewGRPCClient(cfg GRPCConfig, log coordinator.Logger) (*GRPCClient, error) {
+	cfg.applyDefaults()
+	if err := requireToken(cfg.Addr, cfg.Token); err != nil {
+		return nil, err
+	}
+	return &GRPCClient{
+		cfg:   cfg,
+		log:   log,
+		board: newTaskBoard("grpc", cfg.QueueSize, cfg.ResultTTL, log),
+	}, nil
+}
+
+// Register 把 TaskService 注册到已有的 gRPC 服务上（不含令牌校验），便于挂载或在测试中使用。
+func (g *GRPCClient) Register(registrar grpc.ServiceRegistrar) {
+	taskpb.RegisterTaskServiceServer(registrar, &taskService{board: g.board})
+}
+
+// SubscribeCancellations 把 CancelTask 对运行中任务的取消请求转交给协调器。
+func (g *GRPCClient) SubscribeCancellations(ctx context.Context, out chan<- string) error {
+	return g.board.subscribeCancellations(ctx, out)
+}
+
+// SubscribeTasks 启动 gRPC 服务并把提交的任务依次交给协调器，上下文取消时优雅关闭服务。
+func (g *GRPCClient) SubscribeTasks(ctx context.Context, out chan<- coordinator.TaskRequest) error {
+	ln, err := net.Listen("tcp", g.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", g.cfg.Addr, err)
+	}
+	server := grpc.NewServer(
+		grpc.MaxRecvMsgSize(int(g.cfg.MaxUploadBytes)),
+		grpc.UnaryInterceptor(g.authenticateUnary),
+		grpc.StreamInterceptor(g.authenticateStream),
+	)
+	g.Register(server)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(ln)
+	}()
+	g.log.Infof("grpc: task service listening on %s", ln.Addr())
+
+	for {
+		select {
+		case <-ctx.Done():
+			g.shutdown(server)
+			return ctx.Err()
+		case err := <-errCh:
+			return fmt.Errorf("grpc task service: %w", err)
+		case task := <-g.board.queue:
+			if err := g.board.deliver(ctx, task, out); err != nil {
+				g.shutdown(server)
+				return err
+			}
+		}
+	}
+}
+
+// shutdown 等待进行中的调用结束，WatchResults 等长连接最多等待 5 秒后强制关闭。
+func (g *GRPCClient) shutdown(server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		g.log.Warnf("grpc: graceful stop timed out, closing open streams")
+		server.Stop()
+	}
+}
+
+// AckTask 把任务标记为运行中。
+func (g *GRPCClient) AckTask(ctx context.Context, taskID string) error {
+	g.board.setStatus(taskID, statusRunning, nil)
+	return nil
+}
+
+// PublishResult 保存结果并推送给 GetTask/WatchResults 的等待者。
+func (g *GRPCClient) PublishResult(ctx context.Context, result coordinator.TaskResult) error {
+	g.board.publish(result)
+	return nil
+}
+
+// ModuleSource 包装 IPFS 客户端，使协调器能按 CID 读取通过 SubmitTask 上传的模块。
+func (g *GRPCClient) ModuleSource(inner coordinator.IPFSClient) coordinator.IPFSClient {
+	return &uploadedModules{board: g.board, inner: inner}
+}
+
+func (g *GRPCClient) authenticateUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := g.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g *GRPCClient) authenticateStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := g.authorize(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authorize 在配置了 Token 时校验 authorization 元数据；未配置时 NewGRPCClient 已确保只监听回环地址。
+func (g *GRPCClient) authorize(ctx context.Context) error {
+	if g.cfg.Token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	want := []byte("Bearer " + g.cfg.Token)
+	for _, got := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(got), want) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+func (s *taskService) SubmitTask(ctx context.Context, req *taskpb.SubmitTaskRequest) (*taskpb.Task, error) {
+	task, err := taskFromProto(req.GetTask())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	module := req.GetModule()
+	if len(module) == 0 {
+		module = nil
+	}
+	view, err := s.board.submit(task, module)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(view), nil
+}
+
+func (s *taskService) GetTask(ctx context.Context, req *taskpb.GetTaskRequest) (*taskpb.Task, error) {
+	view, _, ok := s.board.snapshot(req.GetTaskId())
+	if !ok {
+		return nil, grpcError(errTaskNotFound)
+	}
+	return taskToProto(view), nil
+}
+
+func (s *taskService) CancelTask(ctx context.Context, req *taskpb.CancelTaskRequest) (*taskpb.Task, error) {
+	view, err := s.board.cancel(req.GetTaskId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return taskToProto(view), nil
+}
+
+// WatchResults 未指定 task_ids 时推送此后结束的所有任务直到客户端断开；指定时先推送其中已结束的任务，
+// 全部推送完后关闭流，未知的 TaskID 返回 NOT_FOUND。
+func (s *taskService) WatchResults(req *taskpb.WatchResultsRequest, stream grpc.ServerStreamingServer[taskpb.Task]) error {
+	ctx := stream.Context()
+	seq, finished := s.board.watch()
+
+	pending := map[string]bool{}
+	for _, id := range req.GetTaskIds() {
+		if _, _, ok := s.board.snapshot(id); !ok {
+			return status.Errorf(codes.NotFound, "task %s not found", id)
+		}
+		pending[id] = true
+	}
+	if len(pending) > 0 {
+		for {
+			for id := range pending {
+				view, _, ok := s.board.snapshot(id)
+				if !ok {
+					return status.Errorf(codes.NotFound, "task %s expired", id)
+				}
+				if !terminal(view.Status) {
+					continue
+				}
+				if err := stream.Send(taskToProto(view)); err != nil {
+					return err
+				}
+				delete(pending, id)
+			}
+			if len(pending) == 0 {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-finished:
+				_, finished = s.board.watch()
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-finished:
+		}
+		var views []taskView
+		views, seq, finished = s.board.finishedSince(seq)
+		for _, view := range views {
+			if err := stream.Send(taskToProto(view)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcError 把任务表的错误映射为 gRPC 状态码。
+func grpcError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, errInvalidTask):
+		code = codes.InvalidArgument
+	case errors.Is(err, errTaskExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, errQueueFull), errors.Is(err, errCancelBusy):
+		code = codes.ResourceExhausted
+	case errors.Is(err, errTaskNotFound):
+		code = codes.NotFound
+	case errors.Is(err, errTaskFinished):
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, err.Error())
+}
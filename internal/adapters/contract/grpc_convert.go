@@ -0,0 +1,115 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"executor/api/taskpb"
+	"executor/internal/coordinator"
+)
+
+var taskStatuses = map[string]taskpb.TaskStatus{
+	statusQueued:    taskpb.TaskStatus_TASK_STATUS_QUEUED,
+	statusRunning:   taskpb.TaskStatus_TASK_STATUS_RUNNING,
+	statusSucceeded: taskpb.TaskStatus_TASK_STATUS_SUCCEEDED,
+	statusFailed:    taskpb.TaskStatus_TASK_STATUS_FAILED,
+	statusCancelled: taskpb.TaskStatus_TASK_STATUS_CANCELLED,
+}
+
+// taskFromProto 把 protobuf 任务转换为 TaskRequest，input_json 必须是合法 JSON。
+func taskFromProto(p *taskpb.TaskRequest) (coordinator.TaskRequest, error) {
+	task := coordinator.TaskRequest{
+		TaskID:         p.GetTaskId(),
+		WasmCID:        p.GetWasmCid(),
+		InputCID:       p.GetInputCid(),
+		Entry:          p.GetEntry(),
+		Args:           p.GetArgs(),
+		ResultMetadata: p.GetResultMetadata(),
+		DataCID:        p.GetDataCid(),
+	}
+	if raw := p.GetInputJson(); len(raw) > 0 {
+		if !json.Valid(raw) {
+			return task, errors.New("input_json is not valid json")
+		}
+		task.InputJSON = raw
+	}
+	if d := p.GetTimeout(); d != nil {
+		task.Timeout = coordinator.Duration(d.AsDuration())
+	}
+	if t := p.GetDeadline(); t != nil {
+		task.Deadline = t.AsTime()
+	}
+	return task, nil
+}
+
+func taskRequestToProto(task coordinator.TaskRequest) *taskpb.TaskRequest {
+	p := &taskpb.TaskRequest{
+		TaskId:         task.TaskID,
+		WasmCid:        task.WasmCID,
+		InputCid:       task.InputCID,
+		Entry:          task.Entry,
+		Args:           task.Args,
+		InputJson:      task.InputJSON,
+		ResultMetadata: task.ResultMetadata,
+		DataCid:        task.DataCID,
+	}
+	if task.Timeout > 0 {
+		p.Timeout = durationpb.New(time.Duration(task.Timeout))
+	}
+	p.Deadline = timestampOrNil(task.Deadline)
+	return p
+}
+
+func taskToProto(view taskView) *taskpb.Task {
+	p := &taskpb.Task{
+		Request:     taskRequestToProto(view.request),
+		Status:      taskStatuses[view.Status],
+		SubmittedAt: timestamppb.New(view.SubmittedAt),
+		UpdatedAt:   timestamppb.New(view.UpdatedAt),
+	}
+	if view.Result != nil {
+		p.Result = resultToProto(*view.Result)
+	}
+	return p
+}
+
+func resultToProto(rec resultRecord) *taskpb.TaskResult {
+	return &taskpb.TaskResult{
+		TaskId:        rec.TaskID,
+		Success:       rec.Success,
+		OutputValue:   rec.OutputValue,
+		Entry:         rec.Entry,
+		Args:          typedValuesToProto(rec.Args),
+		Results:       typedValuesToProto(rec.Results),
+		OutputBytes:   rec.OutputBytes,
+		Logs:          rec.Logs,
+		StartedAt:     timestampOrNil(rec.StartedAt),
+		FinishedAt:    timestampOrNil(rec.FinishedAt),
+		Error:         rec.Error,
+		Metadata:      rec.Metadata,
+		FailureReason: string(rec.FailureReason),
+		ResultCid:     rec.ResultCID,
+	}
+}
+
+func typedValuesToProto(values []coordinator.TypedValue) []*taskpb.TypedValue {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]*taskpb.TypedValue, len(values))
+	for i, v := range values {
+		out[i] = &taskpb.TypedValue{Type: v.Type, ValueJson: string(v.Value), Raw: v.Raw}
+	}
+	return out
+}
+
+func timestampOrNil(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
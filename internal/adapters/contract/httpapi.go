@@ -0,0 +1,337 @@
+package contract
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+
+	"executor/internal/coordinator"
+)
+
+const (
+	defaultHTTPAddr       = ":8080"
+	defaultHTTPQueueSize  = 64
+	defaultMaxUploadBytes = 64 << 20
+	defaultResultTTL      = time.Hour
+	// maxLongPoll 限制 GET /tasks/{id}?wait= 的最长等待时间。
+	maxLongPoll = 5 * time.Minute
+	// multipartMemory 是解析 multipart 表单时保存在内存中的上限，超出部分写入临时文件。
+	multipartMemory = 8 << 20
+)
+
+// HTTPConfig 描述任务提交 HTTP API。
+type HTTPConfig struct {
+	// Addr 是监听地址。
+	Addr string
+	// Token 非空时要求请求携带 Authorization: Bearer <Token>；监听非回环地址时必须设置。
+	Token string
+	// QueueSize 是等待协调器领取的任务上限，队列满时 POST /tasks 返回 503。
+	QueueSize int
+	// MaxUploadBytes 是单次提交（含上传模块）的大小上限。
+	MaxUploadBytes int64
+	// ResultTTL 是已完成任务的状态与结果的保留时长。
+	ResultTTL time.Duration
+}
+
+func (c *HTTPConfig) applyDefaults() {
+	if c.Addr == "" {
+		c.Addr = defaultHTTPAddr
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultHTTPQueueSize
+	}
+	if c.MaxUploadBytes <= 0 {
+		c.MaxUploadBytes = defaultMaxUploadBytes
+	}
+	if c.ResultTTL <= 0 {
+		c.ResultTTL = defaultResultTTL
+	}
+}
+
+// HTTPClient 是以 HTTP API 为任务来源的 ContractClient：
+//
+//	POST /tasks              提交 TaskRequest JSON（可内联 input_json 与 base64 的 module），
+//	                         或 multipart 表单（task 字段为 JSON，module 为模块文件）
+//	GET  /tasks/{id}         查询状态与结果，?wait=30s 长轮询直到任务结束
+//	GET  /tasks/{id}/events  以 SSE 推送状态变化，任务结束后关闭
+//	DELETE /tasks/{id}       取消任务，运行中的任务由协调器中止并以 cancelled 结束
+//
+// 任务经协调器自身的处理循环执行，结果由 PublishResult 写回。状态只保存在内存中。
+type HTTPClient struct {
+	cfg     HTTPConfig
+	log     coordinator.Logger
+	board   *taskBoard
+	handler http.Handler
+}
+
+// uploadDocument 是 JSON 形式的提交请求，module 为 base64 编码的模块字节。
+type uploadDocument struct {
+	taskDocument
+	Module []byte `json:"module,omitempty"`
+}
+
+// NewHTTPClient 构造 HTTP 任务来源；服务在 SubscribeTasks 中启动。
+func NewHTTPClient(cfg HTTPConfig, log coordinator.Logger) (*HTTPClient, error) {
+	cfg.applyDefaults()
+	if err := requireToken(cfg.Addr, cfg.Token); err != nil {
+		return nil, err
+	}
+	h := &HTTPClient{
+		cfg:   cfg,
+		log:   log,
+		board: newTaskBoard("http", cfg.QueueSize, cfg.ResultTTL, log),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tasks", h.handleSubmit)
+	mux.HandleFunc("GET /tasks/{id}", h.handleGet)
+	mux.HandleFunc("GET /tasks/{id}/events", h.handleEvents)
+	mux.HandleFunc("DELETE /tasks/{id}", h.handleCancel)
+	h.handler = h.authenticate(mux)
+	return h, nil
+}
+
+// Handler 返回 API 的 http.Handler，便于挂载到已有服务或在测试中直接调用。
+func (h *HTTPClient) Handler() http.Handler {
+	return h.handler
+}
+
+// SubscribeCancellations 把 DELETE /tasks/{id} 对运行中任务的取消请求转交给协调器。
+func (h *HTTPClient) SubscribeCancellations(ctx context.Context, out chan<- string) error {
+	return h.board.subscribeCancellations(ctx, out)
+}
+
+// SubscribeTasks 启动 HTTP 服务并把提交的任务依次交给协调器，上下文取消时优雅关闭服务。
+func (h *HTTPClient) SubscribeTasks(ctx context.Context, out chan<- coordinator.TaskRequest) error {
+	ln, err := net.Listen("tcp", h.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", h.cfg.Addr, err)
+	}
+	server := &http.Server{Handler: h.handler, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(ln)
+	}()
+	h.log.Infof("http: task api listening on %s", ln.Addr())
+
+	shutdown := func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			h.log.Warnf("http: shutdown: %v", err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			shutdown()
+			return ctx.Err()
+		case err := <-errCh:
+			return fmt.Errorf("http task api: %w", err)
+		case task := <-h.board.queue:
+			if err := h.board.deliver(ctx, task, out); err != nil {
+				shutdown()
+				return err
+			}
+		}
+	}
+}
+
+// AckTask 把任务标记为运行中。
+func (h *HTTPClient) AckTask(ctx context.Context, taskID string) error {
+	h.board.setStatus(taskID, statusRunning, nil)
+	return nil
+}
+
+// PublishResult 保存结果、唤醒等待者，并释放任务对上传模块的引用。
+func (h *HTTPClient) PublishResult(ctx context.Context, result coordinator.TaskResult) error {
+	h.board.publish(result)
+	return nil
+}
+
+// ModuleSource 包装 IPFS 客户端，使协调器能按 CID 读取通过 API 上传的模块，其余请求交给 inner。
+func (h *HTTPClient) ModuleSource(inner coordinator.IPFSClient) coordinator.IPFSClient {
+	return &uploadedModules{board: h.board, inner: inner}
+}
+
+func (h *HTTPClient) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxUploadBytes)
+	task, module, err := h.decodeSubmission(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	view, err := h.board.submit(task, module)
+	switch {
+	case errors.Is(err, errTaskExists):
+		writeJSON(w, http.StatusConflict, view)
+		return
+	case errors.Is(err, errQueueFull):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/tasks/"+view.TaskID)
+	writeJSON(w, http.StatusAccepted, view)
+}
+
+// decodeSubmission 解析 JSON 或 multipart 形式的提交，返回任务与可选的模块字节。
+func (h *HTTPClient) decodeSubmission(r *http.Request) (coordinator.TaskRequest, []byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		var doc uploadDocument
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&doc); err != nil {
+			return coordinator.TaskRequest{}, nil, fmt.Errorf("decode task: %w", err)
+		}
+		task, err := doc.request()
+		return task, doc.Module, err
+	}
+
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		return coordinator.TaskRequest{}, nil, fmt.Errorf("parse form: %w", err)
+	}
+	defer r.MultipartForm.RemoveAll()
+	var doc taskDocument
+	if raw := r.FormValue("task"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+			return coordinator.TaskRequest{}, nil, fmt.Errorf("decode task: %w", err)
+		}
+	}
+	task, err := doc.request()
+	if err != nil {
+		return coordinator.TaskRequest{}, nil, err
+	}
+	file, _, err := r.FormFile("module")
+	if errors.Is(err, http.ErrMissingFile) {
+		return task, nil, nil
+	}
+	if err != nil {
+		return coordinator.TaskRequest{}, nil, fmt.Errorf("read module: %w", err)
+	}
+	defer file.Close()
+	module, err := io.ReadAll(file)
+	if err != nil {
+		return coordinator.TaskRequest{}, nil, fmt.Errorf("read module: %w", err)
+	}
+	return task, module, nil
+}
+
+func (h *HTTPClient) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	view, changed, ok := h.board.snapshot(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "task not found")
+		return
+	}
+	if wait := r.URL.Query().Get("wait"); wait != "" {
+		d, err := time.ParseDuration(wait)
+		if err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, "invalid wait duration")
+			return
+		}
+		timer := time.NewTimer(min(d, maxLongPoll))
+		defer timer.Stop()
+		for !terminal(view.Status) {
+			select {
+			case <-changed:
+				view, changed, _ = h.board.snapshot(id)
+				continue
+			case <-timer.C:
+			case <-r.Context().Done():
+			}
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (h *HTTPClient) handleEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	view, changed, ok := h.board.snapshot(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "task not found")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for {
+		payload, _ := json.Marshal(view)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", view.Status, payload)
+		flusher.Flush()
+		if terminal(view.Status) {
+			return
+		}
+		select {
+		case <-changed:
+			view, changed, _ = h.board.snapshot(id)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleCancel 取消任务：排队中的任务返回 200，运行中的任务返回 202 并在协调器中止后变为 cancelled。
+func (h *HTTPClient) handleCancel(w http.ResponseWriter, r *http.Request) {
+	view, err := h.board.cancel(r.PathValue("id"))
+	switch {
+	case errors.Is(err, errTaskNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, errTaskFinished):
+		writeJSON(w, http.StatusConflict, view)
+	case errors.Is(err, errCancelBusy):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	case view.Status == statusCancelled:
+		writeJSON(w, http.StatusOK, view)
+	default:
+		writeJSON(w, http.StatusAccepted, view)
+	}
+}
+
+// authenticate 在配置了 Token 时校验 Bearer 令牌；未配置时 NewHTTPClient 已确保只监听回环地址。
+func (h *HTTPClient) authenticate(next http.Handler) http.Handler {
+	if h.cfg.Token == "" {
+		return next
+	}
+	want := []byte("Bearer " + h.cfg.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
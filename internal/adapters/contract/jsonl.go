@@ -0,0 +1,273 @@
+package contract
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"executor/internal/coordinator"
+)
+
+const (
+	defaultJSONLPollInterval = time.Second
+	// maxJSONLLine 限制单行任务的长度，超长的行会被跳过。
+	maxJSONLLine = 4 << 20
+	jsonlSuffix  = ".jsonl"
+)
+
+// JSONLConfig 描述基于 JSONL 文件的任务来源。
+type JSONLConfig struct {
+	// Path 是任务文件，或包含若干 *.jsonl 任务文件的目录（按文件名顺序读取）。
+	Path string
+	// ResultsPath 是结果文件，默认为与 Path 同级的 <名称>.results.jsonl。
+	ResultsPath string
+	// OffsetPath 是记录读取进度的旁路文件，默认为 <Path>.offset。
+	OffsetPath string
+	// PollInterval 是读到文件末尾后检查新内容的间隔。
+	PollInterval time.Duration
+}
+
+// JSONLClient 持续读取（tail）JSONL 文件中的任务，每行一个 TaskRequest，结果逐行追加到结果文件。
+// 读取进度按文件记录在旁路文件中，重启后从上次位置继续；不需要区块链即可驱动批量任务。
+type JSONLClient struct {
+	cfg JSONLConfig
+	dir bool
+	log coordinator.Logger
+
+	resultsMu sync.Mutex
+}
+
+// jsonlOffset 是单个任务文件的读取进度。
+type jsonlOffset struct {
+	Offset int64 `json:"offset"`
+	Line   int   `json:"line"`
+	// Generation 在从头读取文件时由文件大小与修改时间生成，文件被截断或轮转后随之改变，
+	// 使缺省 TaskID 不会与旧文件中已发布的任务重复。
+	Generation string `json:"generation,omitempty"`
+}
+
+// NewJSONLClient 校验路径并补全默认的结果文件与进度文件位置。
+func NewJSONLClient(cfg JSONLConfig, log coordinator.Logger) (*JSONLClient, error) {
+	cfg.Path = strings.TrimRight(strings.TrimSpace(cfg.Path), string(filepath.Separator))
+	if cfg.Path == "" {
+		return nil, errors.New("task file path is empty")
+	}
+	info, err := os.Stat(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("task file: %w", err)
+	}
+	if cfg.ResultsPath == "" {
+		cfg.ResultsPath = strings.TrimSuffix(cfg.Path, jsonlSuffix) + ".results" + jsonlSuffix
+	}
+	if cfg.OffsetPath == "" {
+		cfg.OffsetPath = cfg.Path + ".offset"
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultJSONLPollInterval
+	}
+	return &JSONLClient{cfg: cfg, dir: info.IsDir(), log: log}, nil
+}
+
+// SubscribeTasks 依次读取任务文件中的完整行并投递；读到末尾后按 PollInterval 等待追加内容。
+// 尚未以换行结束的行会等到写完后再读取；文件变短时视为被截断或轮转，从头重新读取。
+func (j *JSONLClient) SubscribeTasks(ctx context.Context, out chan<- coordinator.TaskRequest) error {
+	offsets, err := j.loadOffsets()
+	if err != nil {
+		return err
+	}
+	j.log.Infof("jsonl: reading tasks from %s, results to %s", j.cfg.Path, j.cfg.ResultsPath)
+	for {
+		files, err := j.taskFiles()
+		if err != nil {
+			j.log.Warnf("jsonl: list task files: %v", err)
+		}
+		for _, path := range files {
+			if err := j.readFile(ctx, path, offsets, out); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				j.log.Warnf("jsonl: read %s: %v", path, err)
+			}
+		}
+		if err := sleepCtx(ctx, j.cfg.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// AckTask 只记录日志，文件来源不需要确认。
+func (j *JSONLClient) AckTask(ctx context.Context, taskID string) error {
+	j.log.Infof("jsonl: ack task %s", taskID)
+	return nil
+}
+
+// PublishResult 把结果作为一行 JSON 追加到结果文件并 fsync。
+func (j *JSONLClient) PublishResult(ctx context.Context, result coordinator.TaskResult) error {
+	payload, err := json.Marshal(newResultRecord(result))
+	if err != nil {
+		return fmt.Errorf("encode result %s: %w", result.TaskID, err)
+	}
+
+	j.resultsMu.Lock()
+	defer j.resultsMu.Unlock()
+	f, err := os.OpenFile(j.cfg.ResultsPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open results file: %w", err)
+	}
+	if _, err := f.Write(append(payload, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write result %s: %w", result.TaskID, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync results file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close results file: %w", err)
+	}
+	j.log.Infof("jsonl: task %s result written (success=%t)", result.TaskID, result.Success)
+	return nil
+}
+
+// taskFiles 返回需要读取的任务文件：单文件模式为 Path 本身，目录模式为其中按名称排序的 *.jsonl。
+func (j *JSONLClient) taskFiles() ([]string, error) {
+	if !j.dir {
+		return []string{j.cfg.Path}, nil
+	}
+	entries, err := os.ReadDir(j.cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), jsonlSuffix) {
+			files = append(files, filepath.Join(j.cfg.Path, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readFile 从记录的位置读取 path 中新增的完整行，每投递一个任务就保存进度。
+func (j *JSONLClient) readFile(ctx context.Context, path string, offsets map[string]jsonlOffset, out chan<- coordinator.TaskRequest) error {
+	key := filepath.Base(path)
+	pos := offsets[key]
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < pos.Offset {
+		j.log.Warnf("jsonl: %s shrank from %d to %d bytes, reading from the start", path, pos.Offset, info.Size())
+		pos = jsonlOffset{}
+	}
+	if info.Size() == pos.Offset {
+		return nil
+	}
+	if pos.Offset == 0 {
+		pos.Generation = fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+	}
+	if _, err := f.Seek(pos.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// 末尾没有换行的行可能仍在写入，下次再读。
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		pos.Offset += int64(len(line))
+		pos.Line++
+
+		task, skip, perr := j.parseLine(key, pos, line)
+		if perr != nil {
+			j.log.Warnf("jsonl: %s:%d: %v", path, pos.Line, perr)
+		}
+		if !skip && perr == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case out <- task:
+				j.log.Infof("jsonl: task %s from %s:%d", task.TaskID, key, pos.Line)
+			}
+		}
+		offsets[key] = pos
+		j.saveOffsets(offsets)
+	}
+}
+
+// parseLine 解码 pos.Line 行的任务；空行与注释行返回 skip。
+// 缺少 task_id 时以 <文件名>-<行号>-<摘要> 命名，摘要覆盖文件代次与行内容，截断或轮转后的新文件不会复用旧 ID。
+func (j *JSONLClient) parseLine(file string, pos jsonlOffset, line []byte) (coordinator.TaskRequest, bool, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] == '#' {
+		return coordinator.TaskRequest{}, true, nil
+	}
+	if len(line) > maxJSONLLine {
+		return coordinator.TaskRequest{}, false, fmt.Errorf("line longer than %d bytes", maxJSONLLine)
+	}
+	var t taskDocument
+	if err := json.Unmarshal(line, &t); err != nil {
+		return coordinator.TaskRequest{}, false, fmt.Errorf("decode task: %w", err)
+	}
+	task, err := t.request()
+	if err != nil {
+		return coordinator.TaskRequest{}, false, err
+	}
+	if task.WasmCID == "" {
+		return coordinator.TaskRequest{}, false, errors.New("wasm_cid is required")
+	}
+	if task.TaskID == "" {
+		sum := sha256.Sum256(append([]byte(pos.Generation+"\n"), line...))
+		task.TaskID = fmt.Sprintf("%s-%d-%s", strings.TrimSuffix(file, jsonlSuffix), pos.Line, hex.EncodeToString(sum[:6]))
+	}
+	return task, false, nil
+}
+
+func (j *JSONLClient) loadOffsets() (map[string]jsonlOffset, error) {
+	offsets := map[string]jsonlOffset{}
+	payload, err := os.ReadFile(j.cfg.OffsetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return offsets, nil
+		}
+		return nil, fmt.Errorf("read offsets %s: %w", j.cfg.OffsetPath, err)
+	}
+	if err := json.Unmarshal(payload, &offsets); err != nil {
+		return nil, fmt.Errorf("decode offsets %s: %w", j.cfg.OffsetPath, err)
+	}
+	j.log.Infof("jsonl: resuming from %s", j.cfg.OffsetPath)
+	return offsets, nil
+}
+
+func (j *JSONLClient) saveOffsets(offsets map[string]jsonlOffset) {
+	payload, err := json.Marshal(offsets)
+	if err == nil {
+		err = writeFileAtomic(j.cfg.OffsetPath, payload)
+	}
+	if err != nil {
+		j.log.Warnf("jsonl: save offsets: %v", err)
+	}
+}
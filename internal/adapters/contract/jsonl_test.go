@@ -0,0 +1,60 @@
+package contract
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"executor/internal/coordinator"
+)
+
+// readTasks 读取 path 中新增的任务并返回其 TaskID。
+func readTasks(t *testing.T, j *JSONLClient, path string, offsets map[string]jsonlOffset) []string {
+	t.Helper()
+	out := make(chan coordinator.TaskRequest, 16)
+	if err := j.readFile(context.Background(), path, offsets, out); err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+	var ids []string
+	for task := range out {
+		ids = append(ids, task.TaskID)
+	}
+	return ids
+}
+
+// TestJSONLDefaultIDsChangeAfterRotation 确认文件被截断重写后，没有 task_id 的行不会复用旧文件的 ID。
+func TestJSONLDefaultIDsChangeAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.jsonl")
+	content := []byte("{\"wasm_cid\":\"bafymod\"}\n{\"task_id\":\"named\",\"wasm_cid\":\"bafymod\"}\n")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	j, err := NewJSONLClient(JSONLConfig{Path: path}, testLogger{t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := map[string]jsonlOffset{}
+	first := readTasks(t, j, path, offsets)
+	if len(first) != 2 || first[1] != "named" {
+		t.Fatalf("first read = %v", first)
+	}
+	if again := readTasks(t, j, path, offsets); len(again) != 0 {
+		t.Fatalf("re-read delivered %v", again)
+	}
+
+	// 轮转：新文件的第一行内容与旧文件相同，但更短，读取进度被重置。
+	if err := os.WriteFile(path, content[:len("{\"wasm_cid\":\"bafymod\"}\n")], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	rotated := readTasks(t, j, path, offsets)
+	if len(rotated) != 1 || rotated[0] == first[0] {
+		t.Fatalf("rotated file reused id: first=%v rotated=%v", first, rotated)
+	}
+}
@@ -71,9 +71,12 @@ func (p *PlaceholderClient) AckTask(ctx context.Context, taskID string) error {
 // PublishResult 仅打印任务结果，不与真实合约交互。
 func (p *PlaceholderClient) PublishResult(ctx context.Context, result coordinator.TaskResult) error {
 	if result.Success {
-		p.log.Infof("task %s succeeded, output=%s", result.TaskID, result.OutputValue)
+		p.log.Infof("task %s succeeded, entry=%s results=%v", result.TaskID, result.Entry, result.Results)
 	} else {
-		p.log.Warnf("task %s failed: %v", result.TaskID, result.Error)
+		p.log.Warnf("task %s failed (%s): %v", result.TaskID, result.FailureReason, result.Error)
+	}
+	if result.ResultCID != "" {
+		p.log.Infof("task %s result bundle: %s", result.TaskID, result.ResultCID)
 	}
 	return nil
 }
@@ -0,0 +1,270 @@
+package ipfs
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"executor/internal/cid"
+	"executor/internal/coordinator"
+)
+
+const cacheTempPrefix = ".blob-"
+
+// CachingClient 在任意 IPFSClient 前加一层磁盘缓存：按 CID 存放内容，总大小超限时按 LRU 淘汰，
+// 同一 CID 的并发拉取只会向下游发起一次。只缓存下游成功（已校验）的内容。
+type CachingClient struct {
+	inner    coordinator.IPFSClient
+	dir      string
+	maxBytes int64
+	log      coordinator.Logger
+
+	mu      sync.Mutex
+	lru     *list.List // 元素为 *cacheEntry，队首最近使用
+	entries map[string]*list.Element
+	size    int64
+	calls   map[string]*fetchCall
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// fetchCall 是一次进行中的下游拉取，done 关闭后 data/err 只读。
+type fetchCall struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// NewCachingClient 在 dir 下建立缓存并加载已有条目，maxBytes 为缓存总大小上限。
+func NewCachingClient(inner coordinator.IPFSClient, dir string, maxBytes int64, log coordinator.Logger) (*CachingClient, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("module cache requires an ipfs client")
+	}
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("module cache directory is empty")
+	}
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("module cache size must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create module cache dir: %w", err)
+	}
+	c := &CachingClient{
+		inner:    inner,
+		dir:      dir,
+		maxBytes: maxBytes,
+		log:      log,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+		calls:    map[string]*fetchCall{},
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Unwrap 返回被包装的客户端，便于按可选接口查找下游能力。
+func (c *CachingClient) Unwrap() coordinator.IPFSClient {
+	return c.inner
+}
+
+// FetchModule 优先返回缓存内容；未命中时合并同一 CID 的并发请求，下游成功后写入缓存。
+func (c *CachingClient) FetchModule(ctx context.Context, ref string) ([]byte, error) {
+	key := cacheKey(ref)
+	for {
+		if data, ok := c.lookup(key); ok {
+			c.log.Infof("module cache hit %s (%d bytes)", ref, len(data))
+			return data, nil
+		}
+
+		c.mu.Lock()
+		call, inflight := c.calls[key]
+		if !inflight {
+			call = &fetchCall{done: make(chan struct{})}
+			c.calls[key] = call
+		}
+		c.mu.Unlock()
+
+		if !inflight {
+			c.log.Infof("module cache miss %s", ref)
+			call.data, call.err = c.inner.FetchModule(ctx, ref)
+			if call.err == nil {
+				c.store(key, call.data)
+			}
+			c.mu.Lock()
+			delete(c.calls, key)
+			c.mu.Unlock()
+			close(call.done)
+			return call.data, call.err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-call.done:
+		}
+		// 发起者被取消不代表内容不可用，自身上下文仍有效时重新尝试。
+		if call.err != nil && isContextErr(call.err) && ctx.Err() == nil {
+			continue
+		}
+		return call.data, call.err
+	}
+}
+
+// lookup 读取缓存文件并把条目移到 LRU 队首；文件丢失时移除条目。
+func (c *CachingClient) lookup(key string) ([]byte, bool) {
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.log.Warnf("module cache read %s: %v", path, err)
+		c.mu.Lock()
+		if cur, ok := c.entries[key]; ok && cur == elem {
+			c.remove(elem)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now) // 保留访问顺序，重启后据此恢复 LRU
+	return data, true
+}
+
+// store 原子写入缓存文件并按需淘汰最久未使用的条目；超过上限的单个内容不缓存。
+func (c *CachingClient) store(key string, data []byte) {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		c.log.Warnf("module %s (%d bytes) exceeds cache size %d, not cached", key, size, c.maxBytes)
+		return
+	}
+
+	tmp, err := os.CreateTemp(c.dir, cacheTempPrefix+"*")
+	if err != nil {
+		c.log.Warnf("module cache write %s: %v", key, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		c.log.Warnf("module cache write %s: %v", key, err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		c.log.Warnf("module cache write %s: %v", key, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		c.log.Warnf("module cache write %s: %v", key, err)
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*cacheEntry).size
+		c.lru.Remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, size: size})
+	c.size += size
+	c.evict()
+}
+
+// evict 从 LRU 队尾淘汰直到总大小不超过上限，调用方持有 mu。
+func (c *CachingClient) evict() {
+	for c.size > c.maxBytes {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+		entry := elem.Value.(*cacheEntry)
+		if err := os.Remove(c.path(entry.key)); err != nil && !os.IsNotExist(err) {
+			c.log.Warnf("module cache evict %s: %v", entry.key, err)
+		}
+		c.log.Infof("module cache evicted %s (%d bytes)", entry.key, entry.size)
+		c.remove(elem)
+	}
+}
+
+// remove 从索引中删除条目，调用方持有 mu。
+func (c *CachingClient) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// load 扫描缓存目录重建索引，按修改时间恢复 LRU 顺序并清理残留的临时文件。
+func (c *CachingClient) load() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("read module cache dir: %w", err)
+	}
+	type blob struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var blobs []blob
+	for _, de := range dirEntries {
+		if !de.Type().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(de.Name(), cacheTempPrefix) {
+			_ = os.Remove(filepath.Join(c.dir, de.Name()))
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		blobs = append(blobs, blob{key: de.Name(), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].modTime.Before(blobs[j].modTime) })
+	for _, b := range blobs {
+		c.entries[b.key] = c.lru.PushFront(&cacheEntry{key: b.key, size: b.size})
+		c.size += b.size
+	}
+	c.evict()
+	c.log.Infof("module cache %s: %d entries, %d/%d bytes", c.dir, c.lru.Len(), c.size, c.maxBytes)
+	return nil
+}
+
+func (c *CachingClient) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// cacheKey 把引用规范化为文件名：合法 CID 使用规范字符串，其余引用取 sha256。
+func cacheKey(ref string) string {
+	ref = strings.TrimLeft(strings.TrimSpace(ref), "/")
+	if c, err := cid.Parse(ref); err == nil {
+		return c.String()
+	}
+	sum := sha256.Sum256([]byte(ref))
+	return "ref-" + hex.EncodeToString(sum[:])
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,134 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"executor/internal/cid"
+	"executor/internal/coordinator"
+)
+
+// carV2Pragma 是 CARv2 文件固定的 11 字节前缀（一个 version=2 的 CARv1 头）。
+var carV2Pragma = []byte{0x0a, 0xa1, 0x67, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x02}
+
+const (
+	carV2HeaderSize = 40
+	// maxCARBytes 在模块上限之外为 dag-pb 节点与 CID 留出余量。
+	maxCARBytes = maxModuleBytes + 8<<20
+)
+
+// carArchive 是解析后的 CAR 文件，所有块在解析时都已按其 CID 校验。
+type carArchive struct {
+	roots  []cid.Cid
+	blocks map[string][]byte // 以 multihash 为键，CIDv0/v1 引用同一块时都能命中
+}
+
+// parseCAR 解析 CARv1 或 CARv2（只读取其内嵌的 CARv1 数据段，忽略索引），逐块校验。
+func parseCAR(data []byte) (*carArchive, error) {
+	if bytes.HasPrefix(data, carV2Pragma) {
+		inner, err := carV2Payload(data)
+		if err != nil {
+			return nil, err
+		}
+		data = inner
+	}
+
+	hlen, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < hlen {
+		return nil, errors.New("car: truncated header")
+	}
+	roots, version, err := decodeCARHeader(data[n : n+int(hlen)])
+	if err != nil {
+		return nil, err
+	}
+	if version != 1 {
+		return nil, fmt.Errorf("car: unsupported version %d", version)
+	}
+
+	archive := &carArchive{roots: roots, blocks: map[string][]byte{}}
+	rest := data[n+int(hlen):]
+	for len(rest) > 0 {
+		size, n := binary.Uvarint(rest)
+		if n <= 0 || uint64(len(rest)-n) < size {
+			return nil, errors.New("car: truncated section")
+		}
+		if size == 0 {
+			// CARv2 数据段后可能有零填充。
+			break
+		}
+		section := rest[n : n+int(size)]
+		rest = rest[n+int(size):]
+
+		c, cn, err := cid.Decode(section)
+		if err != nil {
+			return nil, fmt.Errorf("car: %w", err)
+		}
+		block := section[cn:]
+		if err := c.Verify(block); err != nil {
+			return nil, fmt.Errorf("%w: car: %v", coordinator.ErrIntegrity, err)
+		}
+		archive.blocks[string(c.Multihash())] = block
+	}
+	return archive, nil
+}
+
+// carV2Payload 根据 CARv2 头中的 data offset/size 截取内嵌的 CARv1。
+func carV2Payload(data []byte) ([]byte, error) {
+	header := data[len(carV2Pragma):]
+	if len(header) < carV2HeaderSize {
+		return nil, errors.New("car: truncated v2 header")
+	}
+	offset := binary.LittleEndian.Uint64(header[16:24])
+	size := binary.LittleEndian.Uint64(header[24:32])
+	if offset > uint64(len(data)) || size > uint64(len(data))-offset {
+		return nil, errors.New("car: v2 data section out of range")
+	}
+	return data[offset : offset+size], nil
+}
+
+// decodeCARHeader 解码 DAG-CBOR 头 {roots: [CID...], version: n}。
+func decodeCARHeader(b []byte) ([]cid.Cid, uint64, error) {
+	v, rest, err := decodeCBOR(b, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("car header: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, 0, errors.New("car header: trailing bytes")
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, 0, errors.New("car header: not a map")
+	}
+	version, _ := m["version"].(uint64)
+	var roots []cid.Cid
+	if list, ok := m["roots"].([]any); ok {
+		for _, item := range list {
+			link, ok := item.(cborLink)
+			if !ok {
+				return nil, 0, errors.New("car header: root is not a cid")
+			}
+			roots = append(roots, cid.Cid(link))
+		}
+	}
+	return roots, version, nil
+}
+
+// fetch 实现 blockFetcher，从归档中读取块。
+func (a *carArchive) fetch(_ context.Context, c cid.Cid) ([]byte, error) {
+	if c.HashCode == cid.Identity {
+		return c.Digest, nil
+	}
+	block, ok := a.blocks[string(c.Multihash())]
+	if !ok {
+		return nil, fmt.Errorf("car: block %s missing", c)
+	}
+	return block, nil
+}
+
+// assemble 从归档中重建 root 指向的 UnixFS 文件。
+func (a *carArchive) assemble(ctx context.Context, root cid.Cid) ([]byte, int, error) {
+	return assembleFile(ctx, root, a.fetch)
+}
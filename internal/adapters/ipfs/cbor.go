@@ -0,0 +1,132 @@
+package ipfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"executor/internal/cid"
+)
+
+// cborLinkTag 是 DAG-CBOR 中表示 CID 链接的标签。
+const cborLinkTag = 42
+
+// cborLink 是 DAG-CBOR 中解码出的 CID 链接。
+type cborLink cid.Cid
+
+const maxCBORDepth = 16
+
+// decodeCBOR 解码 DAG-CBOR 的一个子集（整数、字节串、文本、数组、文本键映射、CID 标签与简单值），
+// 足够读取 CAR 头。返回值类型为 uint64、int64、[]byte、string、[]any、map[string]any、cborLink、bool 或 nil。
+func decodeCBOR(b []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errors.New("cbor: nesting too deep")
+	}
+	if len(b) == 0 {
+		return nil, nil, errors.New("cbor: unexpected end")
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	arg, rest, err := cborArgument(b[1:], info)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch major {
+	case 0:
+		return arg, rest, nil
+	case 1:
+		return -1 - int64(arg), rest, nil
+	case 2, 3:
+		if uint64(len(rest)) < arg {
+			return nil, nil, errors.New("cbor: truncated string")
+		}
+		if major == 2 {
+			return rest[:arg], rest[arg:], nil
+		}
+		return string(rest[:arg]), rest[arg:], nil
+	case 4:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.New("cbor: truncated array")
+		}
+		list := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item any
+			item, rest, err = decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			list = append(list, item)
+		}
+		return list, rest, nil
+	case 5:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.New("cbor: truncated map")
+		}
+		m := make(map[string]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, val any
+			key, rest, err = decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, nil, errors.New("cbor: map key is not a string")
+			}
+			val, rest, err = decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[k] = val
+		}
+		return m, rest, nil
+	case 6:
+		val, rest, err := decodeCBOR(rest, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if arg != cborLinkTag {
+			return nil, nil, fmt.Errorf("cbor: unsupported tag %d", arg)
+		}
+		raw, ok := val.([]byte)
+		if !ok || len(raw) == 0 || raw[0] != 0x00 {
+			return nil, nil, errors.New("cbor: malformed cid link")
+		}
+		c, n, err := cid.Decode(raw[1:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("cbor: %w", err)
+		}
+		if n != len(raw)-1 {
+			return nil, nil, errors.New("cbor: trailing bytes in cid link")
+		}
+		return cborLink(c), rest, nil
+	default:
+		switch info {
+		case 20:
+			return false, rest, nil
+		case 21:
+			return true, rest, nil
+		case 22:
+			return nil, rest, nil
+		}
+		return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+	}
+}
+
+// cborArgument 读取头字节之后的参数（长度或整数值）。
+func cborArgument(b []byte, info byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24 && len(b) >= 1:
+		return uint64(b[0]), b[1:], nil
+	case info == 25 && len(b) >= 2:
+		return uint64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case info == 26 && len(b) >= 4:
+		return uint64(binary.BigEndian.Uint32(b)), b[4:], nil
+	case info == 27 && len(b) >= 8:
+		return binary.BigEndian.Uint64(b), b[8:], nil
+	case info > 27:
+		return 0, nil, fmt.Errorf("cbor: unsupported additional info %d", info)
+	}
+	return 0, nil, errors.New("cbor: truncated argument")
+}
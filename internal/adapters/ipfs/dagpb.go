@@ -0,0 +1,178 @@
+package ipfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"executor/internal/cid"
+)
+
+// UnixFS 节点类型，见 unixfs.proto。
+const (
+	unixfsRaw       = 0
+	unixfsDirectory = 1
+	unixfsFile      = 2
+	unixfsHAMTShard = 5
+)
+
+// pbLink 是 dag-pb 节点中的一条链接。
+type pbLink struct {
+	Cid   cid.Cid
+	Name  string
+	Tsize uint64
+}
+
+// pbNode 是解码后的 dag-pb 节点。
+type pbNode struct {
+	Links []pbLink
+	Data  []byte
+}
+
+// unixfsData 是 dag-pb 节点 Data 字段中的 UnixFS 元数据。
+type unixfsData struct {
+	Type       uint64
+	Data       []byte
+	FileSize   uint64
+	BlockSizes []uint64
+}
+
+// decodePBNode 按 dag-pb 规范解码节点：字段 2 为链接，字段 1 为 Data。
+func decodePBNode(b []byte) (pbNode, error) {
+	var node pbNode
+	err := walkProto(b, func(field int, wire int, v uint64, data []byte) error {
+		switch {
+		case field == 1 && wire == 2:
+			node.Data = data
+		case field == 2 && wire == 2:
+			link, err := decodePBLink(data)
+			if err != nil {
+				return err
+			}
+			node.Links = append(node.Links, link)
+		default:
+			return fmt.Errorf("dag-pb: unexpected field %d", field)
+		}
+		return nil
+	})
+	return node, err
+}
+
+func decodePBLink(b []byte) (pbLink, error) {
+	var (
+		link    pbLink
+		hasHash bool
+	)
+	err := walkProto(b, func(field int, wire int, v uint64, data []byte) error {
+		switch {
+		case field == 1 && wire == 2:
+			c, n, err := cid.Decode(data)
+			if err != nil {
+				return fmt.Errorf("dag-pb link: %w", err)
+			}
+			if n != len(data) {
+				return errors.New("dag-pb link: trailing bytes after cid")
+			}
+			link.Cid = c
+			hasHash = true
+		case field == 2 && wire == 2:
+			link.Name = string(data)
+		case field == 3 && wire == 0:
+			link.Tsize = v
+		default:
+			return fmt.Errorf("dag-pb link: unexpected field %d", field)
+		}
+		return nil
+	})
+	if err == nil && !hasHash {
+		err = errors.New("dag-pb link: missing hash")
+	}
+	return link, err
+}
+
+// decodeUnixFS 解码 UnixFS 元数据，忽略本实现不关心的字段（hashType、fanout、mode、mtime）。
+func decodeUnixFS(b []byte) (unixfsData, error) {
+	var fs unixfsData
+	err := walkProto(b, func(field int, wire int, v uint64, data []byte) error {
+		switch {
+		case field == 1 && wire == 0:
+			fs.Type = v
+		case field == 2 && wire == 2:
+			fs.Data = data
+		case field == 3 && wire == 0:
+			fs.FileSize = v
+		case field == 4 && wire == 0:
+			fs.BlockSizes = append(fs.BlockSizes, v)
+		case field == 4 && wire == 2:
+			// packed 编码的 blocksizes。
+			for len(data) > 0 {
+				n, k := binary.Uvarint(data)
+				if k <= 0 {
+					return errors.New("unixfs: bad blocksizes")
+				}
+				fs.BlockSizes = append(fs.BlockSizes, n)
+				data = data[k:]
+			}
+		}
+		return nil
+	})
+	return fs, err
+}
+
+// encodeUnixFSFile 按 go-ipfs 默认参数把小文件编码为单块 dag-pb 节点，用于在本地复算 CIDv0。
+func encodeUnixFSFile(content []byte) []byte {
+	var fs []byte
+	fs = appendProtoVarint(fs, 1, unixfsFile)
+	if len(content) > 0 {
+		fs = appendProtoBytes(fs, 2, content)
+	}
+	fs = appendProtoVarint(fs, 3, uint64(len(content)))
+	return appendProtoBytes(nil, 1, fs)
+}
+
+// walkProto 逐个遍历 protobuf 字段，只支持 varint 与 length-delimited 两种 wire type。
+func walkProto(b []byte, fn func(field int, wire int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("protobuf: bad field key")
+		}
+		b = b[n:]
+		field, wire := int(key>>3), int(key&7)
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return errors.New("protobuf: bad varint")
+			}
+			b = b[n:]
+			if err := fn(field, wire, v, nil); err != nil {
+				return err
+			}
+		case 2:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				return errors.New("protobuf: truncated field")
+			}
+			data := b[n : n+int(size)]
+			b = b[n+int(size):]
+			if err := fn(field, wire, 0, data); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", wire)
+		}
+	}
+	return nil
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoBytes(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
@@ -2,52 +2,347 @@ package ipfs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"executor/internal/cid"
 	"executor/internal/coordinator"
 )
 
 const (
 	defaultGatewayTimeout = 30 * time.Second
 	maxModuleBytes        = 64 << 20 // 64MiB safety limit
+	maxBlockBytes         = 4 << 20  // 单个 IPFS 块的上限
 )
 
+// 网关检索格式，见 GatewayOptions.Format。
+const (
+	FormatRaw = "raw"
+	FormatCAR = "car"
+)
+
+// GatewayOptions 控制多网关的故障切换与竞速行为。
+type GatewayOptions struct {
+	// Format 选择 trustless 检索方式：FormatRaw 逐块请求，FormatCAR 一次请求整个 DAG。
+	Format string
+	// Race 是同时请求的网关数量，1 表示按顺序逐个尝试。
+	Race int
+	// Timeout 是单个网关请求的超时时间。
+	Timeout time.Duration
+	// FailureThreshold 是连续失败多少次后把网关标记为不健康。
+	FailureThreshold int
+	// Cooldown 是不健康网关被降级的时长，期间只作为最后的备选。
+	Cooldown time.Duration
+}
+
+func (o *GatewayOptions) applyDefaults() {
+	if o.Format == "" {
+		o.Format = FormatRaw
+	}
+	if o.Race <= 0 {
+		o.Race = 1
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaultGatewayTimeout
+	}
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = 3
+	}
+	if o.Cooldown <= 0 {
+		o.Cooldown = 30 * time.Second
+	}
+}
+
 // GatewayClient 通过 HTTP Gateway 拉取 Wasm 模块，兼容本地与远程 IPFS 服务。
+// 内容以 trustless 方式逐块获取（?format=raw），每个块都按其 CID 校验后再拼装，不信任网关返回的文件。
+// 配置多个网关时按顺序故障切换（可选竞速），并记录每个网关的健康状态与延迟。
 type GatewayClient struct {
-	baseURL string
-	client  *http.Client
-	log     coordinator.Logger
+	gateways []*gateway
+	opts     GatewayOptions
+	client   *http.Client
+	log      coordinator.Logger
 }
 
-// NewGatewayClient 构造面向 HTTP Gateway 的 IPFS 客户端。
+// NewGatewayClient 构造面向 HTTP Gateway 的 IPFS 客户端，baseURL 可以是逗号分隔的多个网关。
 func NewGatewayClient(baseURL string, log coordinator.Logger) (*GatewayClient, error) {
-	trimmed := strings.TrimSpace(baseURL)
-	if trimmed == "" {
+	return NewGatewayClientWithOptions(strings.Split(baseURL, ","), GatewayOptions{}, log)
+}
+
+// NewGatewayClientWithOptions 按优先级顺序使用 endpoints 中的网关。
+func NewGatewayClientWithOptions(endpoints []string, opts GatewayOptions, log coordinator.Logger) (*GatewayClient, error) {
+	opts.applyDefaults()
+	if opts.Format != FormatRaw && opts.Format != FormatCAR {
+		return nil, fmt.Errorf("unknown gateway format %q (want raw|car)", opts.Format)
+	}
+	var gateways []*gateway
+	for _, ep := range endpoints {
+		trimmed := strings.TrimRight(strings.TrimSpace(ep), "/")
+		if trimmed != "" {
+			gateways = append(gateways, &gateway{baseURL: trimmed})
+		}
+	}
+	if len(gateways) == 0 {
 		return nil, fmt.Errorf("ipfs gateway base url is empty")
 	}
 	return &GatewayClient{
-		baseURL: strings.TrimRight(trimmed, "/"),
+		gateways: gateways,
+		opts:     opts,
 		client: &http.Client{
-			Timeout: defaultGatewayTimeout,
+			Timeout: opts.Timeout,
 		},
 		log: log,
 	}, nil
 }
 
-// FetchModule 通过网关下载指定 CID 的字节流。
-func (g *GatewayClient) FetchModule(ctx context.Context, cid string) ([]byte, error) {
-	if cid == "" {
-		return nil, fmt.Errorf("cid is empty")
+// FetchModule 通过网关下载指定 CID 的内容：raw 块直接校验，dag-pb（UnixFS 文件）逐层拉取子块并校验；
+// FormatCAR 模式下一次取回整个 DAG 的 CAR 并逐块校验。ref 可以是 "<目录 CID>/路径"，路径沿已校验的目录块解析。
+func (g *GatewayClient) FetchModule(ctx context.Context, ref string) ([]byte, error) {
+	root, segments, err := splitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		data   []byte
+		blocks int
+	)
+	if g.opts.Format == FormatCAR {
+		var mu sync.Mutex
+		err = g.fetchCAR(ctx, root, segments, "entity", func(archive *carArchive, target cid.Cid) error {
+			d, n, err := archive.assemble(ctx, target)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			data, blocks = d, n
+			mu.Unlock()
+			return nil
+		})
+	} else {
+		var target cid.Cid
+		target, err = resolvePath(ctx, root, segments, g.fetchBlock)
+		if err == nil {
+			data, blocks, err = assembleFile(ctx, target, g.fetchBlock)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	g.log.Infof("downloaded wasm module %s (%d bytes, %d verified blocks, format=%s) via ipfs gateway", refPath(root, segments), len(data), blocks, g.opts.Format)
+	return data, nil
+}
+
+// FetchDirectory 下载 ref 指向的 UnixFS 目录，返回相对路径到文件内容的映射，所有块均经过校验。
+func (g *GatewayClient) FetchDirectory(ctx context.Context, ref string) (map[string][]byte, error) {
+	root, segments, err := splitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var files map[string][]byte
+	if g.opts.Format == FormatCAR {
+		var mu sync.Mutex
+		err = g.fetchCAR(ctx, root, segments, "all", func(archive *carArchive, target cid.Cid) error {
+			tree, err := fetchTree(ctx, target, archive.fetch)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			files = tree
+			mu.Unlock()
+			return nil
+		})
+	} else {
+		var target cid.Cid
+		target, err = resolvePath(ctx, root, segments, g.fetchBlock)
+		if err == nil {
+			files, err = fetchTree(ctx, target, g.fetchBlock)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	g.log.Infof("downloaded directory %s (%d files, format=%s) via ipfs gateway", refPath(root, segments), len(files), g.opts.Format)
+	return files, nil
+}
+
+// Stats 返回每个网关的请求计数、失败次数与平均延迟。
+func (g *GatewayClient) Stats() []GatewayStats {
+	now := time.Now()
+	stats := make([]GatewayStats, 0, len(g.gateways))
+	for _, gw := range g.gateways {
+		stats = append(stats, gw.stats(now))
+	}
+	return stats
+}
+
+// LogStats 每隔 interval 把各网关的请求数、失败数、平均延迟与健康状态写入日志，直到 ctx 取消。
+func (g *GatewayClient) LogStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, st := range g.Stats() {
+			g.log.Infof("ipfs gateway %s: requests=%d failures=%d avg_latency=%s healthy=%t",
+				st.URL, st.Requests, st.Failures, st.AvgLatency, st.Healthy)
+		}
 	}
-	target := fmt.Sprintf("%s/%s", g.baseURL, strings.TrimLeft(cid, "/"))
+}
+
+// fetchBlock 以 ?format=raw 获取单个块并按 CID 校验；identity CID 无需请求。
+func (g *GatewayClient) fetchBlock(ctx context.Context, c cid.Cid) ([]byte, error) {
+	if c.HashCode == cid.Identity {
+		return c.Digest, nil
+	}
+	return g.tryGateways(ctx, func(ctx context.Context, gw *gateway) ([]byte, error) {
+		target := fmt.Sprintf("%s/%s?format=raw", gw.baseURL, c)
+		block, err := g.get(ctx, target, "application/vnd.ipld.raw", maxBlockBytes)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Verify(block); err != nil {
+			return nil, fmt.Errorf("%w: gateway %s: %v", coordinator.ErrIntegrity, gw.baseURL, err)
+		}
+		return block, nil
+	})
+}
+
+// fetchCAR 以 ?format=car 一次取回路径上的目录块与目标 DAG，校验 CAR 中的每个块并在归档内解析路径后交给 use。
+// use 在某个网关成功时调用；竞速时可能被多个网关调用，但内容均按 CID 校验，结果一致。
+func (g *GatewayClient) fetchCAR(ctx context.Context, root cid.Cid, segments []string, scope string, use func(archive *carArchive, target cid.Cid) error) error {
+	_, err := g.tryGateways(ctx, func(ctx context.Context, gw *gateway) ([]byte, error) {
+		target := fmt.Sprintf("%s/%s?format=car&dag-scope=%s", gw.baseURL, refPath(root, segments), scope)
+		payload, err := g.get(ctx, target, "application/vnd.ipld.car", maxCARBytes)
+		if err != nil {
+			return nil, err
+		}
+		archive, err := parseCAR(payload)
+		if err != nil {
+			if errors.Is(err, coordinator.ErrIntegrity) {
+				err = fmt.Errorf("gateway %s: %w", gw.baseURL, err)
+			}
+			return nil, err
+		}
+		resolved, err := resolvePath(ctx, root, segments, archive.fetch)
+		if err != nil {
+			return nil, fmt.Errorf("gateway %s: %w", gw.baseURL, err)
+		}
+		if err := use(archive, resolved); err != nil {
+			return nil, fmt.Errorf("gateway %s: %w", gw.baseURL, err)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// gatewayRequest 是针对单个网关的一次请求，返回已校验的内容。
+type gatewayRequest func(ctx context.Context, gw *gateway) ([]byte, error)
+
+// tryGateways 先竞速请求前 Race 个网关，失败后按顺序尝试其余网关。
+func (g *GatewayClient) tryGateways(ctx context.Context, req gatewayRequest) ([]byte, error) {
+	order := g.ordered(time.Now())
+	var errs gatewayErrors
+	if n := min(g.opts.Race, len(order)); n > 1 {
+		data, err := g.race(ctx, order[:n], req)
+		if err == nil {
+			return data, nil
+		}
+		errs = append(errs, err)
+		order = order[n:]
+	}
+	for i, gw := range order {
+		data, err := g.attempt(ctx, gw, req)
+		if err == nil {
+			return data, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+		if i < len(order)-1 {
+			g.log.Warnf("gateway %s: %v, trying next gateway", gw.baseURL, err)
+		}
+	}
+	return nil, errs
+}
+
+// race 并行请求多个网关，返回第一个成功的结果并取消其余请求。
+func (g *GatewayClient) race(ctx context.Context, gateways []*gateway, req gatewayRequest) ([]byte, error) {
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		data []byte
+		err  error
+	}
+	results := make(chan outcome, len(gateways))
+	for _, gw := range gateways {
+		go func(gw *gateway) {
+			data, err := g.attempt(raceCtx, gw, req)
+			results <- outcome{data: data, err: err}
+		}(gw)
+	}
+	var errs gatewayErrors
+	for range gateways {
+		res := <-results
+		if res.err == nil {
+			return res.data, nil
+		}
+		errs = append(errs, res.err)
+	}
+	return nil, errs
+}
+
+// attempt 在单个网关上执行请求，同时更新该网关的健康状态与延迟统计。
+func (g *GatewayClient) attempt(ctx context.Context, gw *gateway, req gatewayRequest) ([]byte, error) {
+	start := time.Now()
+	data, err := req(ctx, gw)
+	// 竞速落败或任务取消不计入网关健康状态。
+	if ctx.Err() == nil {
+		if gw.record(time.Since(start), err, g.opts) {
+			g.log.Warnf("gateway %s marked unhealthy for %s: %v", gw.baseURL, g.opts.Cooldown, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ordered 返回尝试顺序：健康网关保持配置顺序在前，处于冷却期的网关排在最后。
+func (g *GatewayClient) ordered(now time.Time) []*gateway {
+	healthy := make([]*gateway, 0, len(g.gateways))
+	var down []*gateway
+	for _, gw := range g.gateways {
+		if gw.healthy(now) {
+			healthy = append(healthy, gw)
+		} else {
+			down = append(down, gw)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// get 发起 GET 请求并读取至多 limit 字节，限流与 5xx 标记为暂时性错误。
+func (g *GatewayClient) get(ctx context.Context, target, accept string, limit int) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
 		return nil, fmt.Errorf("new request: %w", err)
 	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
 
 	resp, err := g.client.Do(req)
 	if err != nil {
@@ -57,18 +352,42 @@ func (g *GatewayClient) FetchModule(ctx context.Context, cid string) ([]byte, er
 
 	if resp.StatusCode != http.StatusOK {
 		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("gateway %s status %s: %s", target, resp.Status, strings.TrimSpace(string(payload)))
+		err := fmt.Errorf("gateway %s status %s: %s", target, resp.Status, strings.TrimSpace(string(payload)))
+		// 限流与 5xx 通常是网关暂时不可用，交给协调器的重试策略处理。
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			return nil, coordinator.Transient(err)
+		}
+		return nil, err
 	}
 
-	reader := io.LimitReader(resp.Body, maxModuleBytes+1)
-	data, err := io.ReadAll(reader)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
 	if err != nil {
 		return nil, fmt.Errorf("read %s: %w", target, err)
 	}
-	if len(data) > int(maxModuleBytes) {
-		return nil, fmt.Errorf("module larger than %d bytes", maxModuleBytes)
+	if len(data) > limit {
+		return nil, fmt.Errorf("%s larger than %d bytes", target, limit)
 	}
-
-	g.log.Infof("downloaded wasm module %s (%d bytes) via ipfs gateway", cid, len(data))
 	return data, nil
 }
+
+// gatewayErrors 汇总各网关的失败，保留每个错误以便 errors.Is/As 识别暂时性错误与校验失败。
+type gatewayErrors []error
+
+func (e gatewayErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("all gateways failed: %s", strings.Join(msgs, "; "))
+}
+
+func (e gatewayErrors) Unwrap() []error { return e }
+
+// isGatewayFault 判断错误是否反映网关自身的问题（不可达、超时、5xx、返回内容不符）。
+// 404 等内容相关的错误不影响网关健康状态。
+func isGatewayFault(err error) bool {
+	return coordinator.IsRetryable(err) || errors.Is(err, coordinator.ErrIntegrity)
+}
@@ -0,0 +1,83 @@
+package ipfs
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"executor/internal/coordinator"
+)
+
+// latencyWeight 是延迟指数滑动平均中新样本的权重。
+const latencyWeight = 0.2
+
+// gateway 保存单个网关的健康状态与统计数据。
+type gateway struct {
+	baseURL string
+
+	mu        sync.Mutex
+	failures  int // 连续的网关故障次数
+	downUntil time.Time
+	requests  int64
+	errors    int64
+	latency   time.Duration // 成功请求的指数滑动平均延迟
+}
+
+// GatewayStats 是单个网关的统计快照。
+type GatewayStats struct {
+	URL        string
+	Requests   int64
+	Failures   int64
+	AvgLatency time.Duration
+	Healthy    bool
+}
+
+func (gw *gateway) healthy(now time.Time) bool {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	return !now.Before(gw.downUntil)
+}
+
+// record 记录一次请求结果，返回本次是否把网关转为不健康。
+// 返回内容与 CID 不符的网关立即降级，其余故障累计到 FailureThreshold 次后降级。
+func (gw *gateway) record(elapsed time.Duration, err error, opts GatewayOptions) bool {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	gw.requests++
+	if err == nil {
+		gw.failures = 0
+		gw.downUntil = time.Time{}
+		if gw.latency == 0 {
+			gw.latency = elapsed
+		} else {
+			gw.latency += time.Duration(latencyWeight * float64(elapsed-gw.latency))
+		}
+		return false
+	}
+	gw.errors++
+	if !isGatewayFault(err) {
+		return false
+	}
+	gw.failures++
+	if errors.Is(err, coordinator.ErrIntegrity) {
+		gw.failures = max(gw.failures, opts.FailureThreshold)
+	}
+	if gw.failures < opts.FailureThreshold {
+		return false
+	}
+	wasHealthy := !time.Now().Before(gw.downUntil)
+	gw.downUntil = time.Now().Add(opts.Cooldown)
+	return wasHealthy
+}
+
+func (gw *gateway) stats(now time.Time) GatewayStats {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	return GatewayStats{
+		URL:        gw.baseURL,
+		Requests:   gw.requests,
+		Failures:   gw.errors,
+		AvgLatency: gw.latency,
+		Healthy:    !now.Before(gw.downUntil),
+	}
+}
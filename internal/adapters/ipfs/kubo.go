@@ -0,0 +1,223 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"executor/internal/cid"
+	"executor/internal/coordinator"
+)
+
+const defaultKuboTimeout = 60 * time.Second
+
+// KuboClient 通过 Kubo（go-ipfs）的 RPC API（默认端口 5001）读写 IPFS。
+// 拉取时 raw 块用 /api/v0/cat 取回后直接校验，dag-pb 文件用 /api/v0/block/get 逐块获取并校验，
+// 每个块只下载一次；同时支持固定（pin/add）与写入（add）内容。
+type KuboClient struct {
+	apiURL string
+	client *http.Client
+	log    coordinator.Logger
+}
+
+// NewKuboClient 构造 Kubo RPC 客户端，apiURL 形如 http://127.0.0.1:5001。
+func NewKuboClient(apiURL string, log coordinator.Logger) (*KuboClient, error) {
+	trimmed := strings.TrimRight(strings.TrimSpace(apiURL), "/")
+	if trimmed == "" {
+		return nil, fmt.Errorf("kubo api url is empty")
+	}
+	trimmed = strings.TrimSuffix(trimmed, "/api/v0")
+	return &KuboClient{
+		apiURL: trimmed,
+		client: &http.Client{Timeout: defaultKuboTimeout},
+		log:    log,
+	}, nil
+}
+
+// FetchModule 读取并校验指定 CID 的文件内容；ref 可以是 "<目录 CID>/路径"，路径沿已校验的目录块解析。
+func (k *KuboClient) FetchModule(ctx context.Context, ref string) ([]byte, error) {
+	root, segments, err := splitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolvePath(ctx, root, segments, k.fetchBlock)
+	if err != nil {
+		return nil, err
+	}
+	ref = refPath(root, segments)
+
+	if target.Codec == cid.Raw {
+		data, err := k.Cat(ctx, target.String())
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyContent(target, data); err != nil {
+			return nil, fmt.Errorf("kubo %s: %w", k.apiURL, err)
+		}
+		k.log.Infof("downloaded wasm module %s (%d bytes) via kubo cat", ref, len(data))
+		return data, nil
+	}
+
+	data, blocks, err := assembleFile(ctx, target, k.fetchBlock)
+	if err != nil {
+		return nil, err
+	}
+	k.log.Infof("downloaded wasm module %s (%d bytes, %d verified blocks) via kubo block/get", ref, len(data), blocks)
+	return data, nil
+}
+
+// FetchDirectory 逐块读取并校验 ref 指向的 UnixFS 目录，返回相对路径到文件内容的映射。
+func (k *KuboClient) FetchDirectory(ctx context.Context, ref string) (map[string][]byte, error) {
+	root, segments, err := splitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolvePath(ctx, root, segments, k.fetchBlock)
+	if err != nil {
+		return nil, err
+	}
+	files, err := fetchTree(ctx, target, k.fetchBlock)
+	if err != nil {
+		return nil, err
+	}
+	k.log.Infof("downloaded directory %s (%d files) via kubo block/get", refPath(root, segments), len(files))
+	return files, nil
+}
+
+// Cat 通过 /api/v0/cat 读取 UnixFS 文件内容，不做校验。
+func (k *KuboClient) Cat(ctx context.Context, ref string) ([]byte, error) {
+	return k.call(ctx, "cat", url.Values{"arg": {ref}}, nil, "", maxModuleBytes)
+}
+
+// BlockGet 通过 /api/v0/block/get 读取单个原始块，不做校验。
+func (k *KuboClient) BlockGet(ctx context.Context, ref string) ([]byte, error) {
+	return k.call(ctx, "block/get", url.Values{"arg": {ref}}, nil, "", maxBlockBytes)
+}
+
+// Pin 通过 /api/v0/pin/add 递归固定内容，避免节点 GC 回收正在使用的模块。
+func (k *KuboClient) Pin(ctx context.Context, ref string) error {
+	// 带路径的引用需要写成 /ipfs/<cid>/路径 才能被 RPC 解析。
+	if root, segments, err := splitRef(ref); err == nil && len(segments) > 0 {
+		ref = "/ipfs/" + refPath(root, segments)
+	}
+	if _, err := k.call(ctx, "pin/add", url.Values{"arg": {ref}}, nil, "", 1<<20); err != nil {
+		return err
+	}
+	k.log.Infof("pinned %s on kubo node", ref)
+	return nil
+}
+
+// Add 通过 /api/v0/add 写入内容（CIDv1、raw leaves 并固定），返回内容的 CID。
+func (k *KuboClient) Add(ctx context.Context, name string, data []byte) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return "", fmt.Errorf("build add request: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("build add request: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("build add request: %w", err)
+	}
+
+	params := url.Values{
+		"cid-version": {"1"},
+		"raw-leaves":  {"true"},
+		"pin":         {"true"},
+		"quieter":     {"true"},
+	}
+	payload, err := k.call(ctx, "add", params, &body, mw.FormDataContentType(), 1<<20)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Hash string `json:"Hash"`
+	}
+	// add 以换行分隔的 JSON 流返回每个文件的结果，单文件时取最后一条。
+	lines := bytes.Split(bytes.TrimSpace(payload), []byte("\n"))
+	if err := json.Unmarshal(lines[len(lines)-1], &resp); err != nil || resp.Hash == "" {
+		return "", fmt.Errorf("decode kubo add response: %q", payload)
+	}
+
+	// 单块内容的 CID 可在本地复算，顺便确认节点存入的就是我们提交的字节。
+	if c, err := cid.Parse(resp.Hash); err == nil && c.Codec == cid.Raw {
+		if err := c.Verify(data); err != nil {
+			return "", fmt.Errorf("%w: kubo add: %v", coordinator.ErrIntegrity, err)
+		}
+	}
+	k.log.Infof("added %s (%d bytes) to kubo as %s", name, len(data), resp.Hash)
+	return resp.Hash, nil
+}
+
+// fetchBlock 读取单个块并按 CID 校验。
+func (k *KuboClient) fetchBlock(ctx context.Context, c cid.Cid) ([]byte, error) {
+	if c.HashCode == cid.Identity {
+		return c.Digest, nil
+	}
+	block, err := k.BlockGet(ctx, c.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Verify(block); err != nil {
+		return nil, fmt.Errorf("%w: kubo %s: %v", coordinator.ErrIntegrity, k.apiURL, err)
+	}
+	return block, nil
+}
+
+// call 以 POST 调用 /api/v0/<command> 并读取至多 limit 字节的响应。
+// Kubo 对命令错误统一返回 500 与 JSON 消息，只有网关类状态码（429/502/503/504）视为暂时性错误。
+func (k *KuboClient) call(ctx context.Context, command string, params url.Values, body io.Reader, contentType string, limit int) ([]byte, error) {
+	target := fmt.Sprintf("%s/api/v0/%s?%s", k.apiURL, command, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kubo %s: %w", command, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		var rpcErr struct {
+			Message string `json:"Message"`
+		}
+		msg := strings.TrimSpace(string(payload))
+		if json.Unmarshal(payload, &rpcErr) == nil && rpcErr.Message != "" {
+			msg = rpcErr.Message
+		}
+		err := fmt.Errorf("kubo %s %s: %s", command, resp.Status, msg)
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return nil, coordinator.Transient(err)
+		}
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("read kubo %s: %w", command, err)
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("kubo %s response larger than %d bytes", command, limit)
+	}
+	// cat/block/get 在流式输出中途出错时通过 trailer 报告。
+	if msg := resp.Trailer.Get("X-Stream-Error"); msg != "" {
+		return nil, fmt.Errorf("kubo %s: %s", command, msg)
+	}
+	return data, nil
+}
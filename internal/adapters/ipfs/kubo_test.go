@@ -0,0 +1,258 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"executor/internal/cid"
+	"executor/internal/coordinator"
+)
+
+// testLogger 把适配器日志写入测试输出。
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Infof(format string, args ...any)  { l.t.Logf("[INFO] "+format, args...) }
+func (l testLogger) Warnf(format string, args ...any)  { l.t.Logf("[WARN] "+format, args...) }
+func (l testLogger) Errorf(format string, args ...any) { l.t.Logf("[ERROR] "+format, args...) }
+
+// fakeKubo 是 Kubo RPC API 的替身：按 CID 保存块，cat 只支持 raw 块，add 以 CIDv1 raw 块写入。
+type fakeKubo struct {
+	mu     sync.Mutex
+	blocks map[string][]byte
+	calls  []string
+	// status 非零时所有请求都以该状态码失败。
+	status int
+}
+
+func newFakeKubo(t *testing.T) (*fakeKubo, *KuboClient) {
+	t.Helper()
+	f := &fakeKubo{blocks: map[string][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	client, err := NewKuboClient(srv.URL+"/api/v0/", testLogger{t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, client
+}
+
+// put 保存块并返回其 CID。
+func (f *fakeKubo) put(codec uint64, block []byte) cid.Cid {
+	c := cid.Sum(codec, block)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocks[c.String()] = block
+	return c
+}
+
+// set 替换已保存的块，用于模拟节点返回被篡改的内容。
+func (f *fakeKubo) set(key string, block []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocks[key] = block
+}
+
+func (f *fakeKubo) called(command string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var args []string
+	for _, call := range f.calls {
+		if cmd, arg, _ := strings.Cut(call, " "); cmd == command {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+func (f *fakeKubo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	command := strings.TrimPrefix(r.URL.Path, "/api/v0/")
+	arg := r.URL.Query().Get("arg")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, command+" "+arg)
+	if f.status != 0 {
+		rpcError(w, f.status, "node unavailable")
+		return
+	}
+	switch command {
+	case "block/get":
+		block, ok := f.blocks[arg]
+		if !ok {
+			rpcError(w, http.StatusInternalServerError, "block was not found locally (offline): ipld: could not find "+arg)
+			return
+		}
+		w.Write(block)
+	case "cat":
+		c, err := cid.Parse(arg)
+		if err != nil || c.Codec != cid.Raw {
+			rpcError(w, http.StatusInternalServerError, "fake only serves raw blocks via cat")
+			return
+		}
+		block, ok := f.blocks[arg]
+		if !ok {
+			rpcError(w, http.StatusInternalServerError, "block not found")
+			return
+		}
+		w.Write(block)
+	case "pin/add":
+		json.NewEncoder(w).Encode(map[string][]string{"Pins": {arg}})
+	case "add":
+		q := r.URL.Query()
+		if q.Get("cid-version") != "1" || q.Get("raw-leaves") != "true" || q.Get("pin") != "true" {
+			rpcError(w, http.StatusBadRequest, "unexpected add options "+r.URL.RawQuery)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			rpcError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		data, _ := io.ReadAll(file)
+		c := cid.Sum(cid.Raw, data)
+		f.blocks[c.String()] = data
+		json.NewEncoder(w).Encode(map[string]string{"Name": header.Filename, "Hash": c.String()})
+	default:
+		rpcError(w, http.StatusNotFound, "unknown command "+command)
+	}
+}
+
+func rpcError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{"Message": msg, "Code": 0, "Type": "error"})
+}
+
+// putFile 以 raw 叶子与一个 UnixFS 文件根节点保存 chunks 组成的文件，返回根 CID。
+func (f *fakeKubo) putFile(chunks ...[]byte) cid.Cid {
+	var fs, block []byte
+	var total uint64
+	for _, chunk := range chunks {
+		total += uint64(len(chunk))
+	}
+	fs = appendProtoVarint(fs, 1, unixfsFile)
+	fs = appendProtoVarint(fs, 3, total)
+	for _, chunk := range chunks {
+		fs = appendProtoVarint(fs, 4, uint64(len(chunk)))
+		var link []byte
+		link = appendProtoBytes(link, 1, f.put(cid.Raw, chunk).Bytes())
+		link = appendProtoBytes(link, 2, nil)
+		link = appendProtoVarint(link, 3, uint64(len(chunk)))
+		block = appendProtoBytes(block, 2, link)
+	}
+	block = appendProtoBytes(block, 1, fs)
+	return f.put(cid.DagPB, block)
+}
+
+func TestKuboFetchRawModuleViaCat(t *testing.T) {
+	f, client := newFakeKubo(t)
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	c := f.put(cid.Raw, module)
+
+	got, err := client.FetchModule(context.Background(), c.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, module) {
+		t.Fatalf("module = %x", got)
+	}
+	if cats := f.called("cat"); len(cats) != 1 || cats[0] != c.String() {
+		t.Errorf("cat calls = %v", cats)
+	}
+
+	f.set(c.String(), []byte("tampered"))
+	if _, err := client.FetchModule(context.Background(), c.String()); !errors.Is(err, coordinator.ErrIntegrity) {
+		t.Fatalf("tampered cat: err = %v, want ErrIntegrity", err)
+	}
+}
+
+func TestKuboFetchMultiBlockModule(t *testing.T) {
+	f, client := newFakeKubo(t)
+	chunks := [][]byte{[]byte("first chunk "), []byte("second chunk "), []byte("last")}
+	root := f.putFile(chunks...)
+
+	got, err := client.FetchModule(context.Background(), root.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Join(chunks, nil); !bytes.Equal(got, want) {
+		t.Fatalf("module = %q, want %q", got, want)
+	}
+	// 每个块只经 block/get 下载一次，不再额外 cat 整个文件。
+	gets := f.called("block/get")
+	seen := map[string]bool{}
+	for _, arg := range gets {
+		if seen[arg] {
+			t.Errorf("block %s fetched twice", arg)
+		}
+		seen[arg] = true
+	}
+	if len(seen) != len(chunks)+1 {
+		t.Errorf("fetched %d distinct blocks, want %d", len(seen), len(chunks)+1)
+	}
+	if cats := f.called("cat"); len(cats) != 0 {
+		t.Errorf("unexpected cat calls %v", cats)
+	}
+
+	leaf := cid.Sum(cid.Raw, chunks[1]).String()
+	f.set(leaf, []byte("second chunk!"))
+	if _, err := client.FetchModule(context.Background(), root.String()); !errors.Is(err, coordinator.ErrIntegrity) {
+		t.Fatalf("tampered leaf: err = %v, want ErrIntegrity", err)
+	}
+}
+
+func TestKuboAddAndPin(t *testing.T) {
+	f, client := newFakeKubo(t)
+	payload := []byte(`{"task_id":"t1","success":true}`)
+
+	ref, err := client.Add(context.Background(), "result.json", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := cid.Sum(cid.Raw, payload).String(); ref != want {
+		t.Fatalf("add returned %s, want %s", ref, want)
+	}
+
+	if err := client.Pin(context.Background(), ref); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Pin(context.Background(), ref+"/lib/module.wasm"); err != nil {
+		t.Fatal(err)
+	}
+	pins := f.called("pin/add")
+	if want := []string{ref, "/ipfs/" + ref + "/lib/module.wasm"}; len(pins) != 2 || pins[0] != want[0] || pins[1] != want[1] {
+		t.Errorf("pin/add calls = %v, want %v", pins, want)
+	}
+}
+
+func TestKuboErrors(t *testing.T) {
+	f, client := newFakeKubo(t)
+	missing := cid.Sum(cid.Raw, []byte("missing")).String()
+
+	_, err := client.FetchModule(context.Background(), missing)
+	if err == nil || !strings.Contains(err.Error(), "block not found") {
+		t.Fatalf("missing block: err = %v", err)
+	}
+	if coordinator.IsRetryable(err) {
+		t.Errorf("command error %v classified as retryable", err)
+	}
+
+	f.mu.Lock()
+	f.status = http.StatusServiceUnavailable
+	f.mu.Unlock()
+	if _, err := client.FetchModule(context.Background(), missing); !coordinator.IsRetryable(err) {
+		t.Errorf("503: err = %v, want retryable", err)
+	}
+}
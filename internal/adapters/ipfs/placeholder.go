@@ -3,9 +3,11 @@ package ipfs
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
+	cidpkg "executor/internal/cid"
 	"executor/internal/coordinator"
 )
 
@@ -23,7 +25,9 @@ func NewPlaceholderClient(dir string, log coordinator.Logger) *PlaceholderClient
 	}
 }
 
-// FetchModule 从磁盘加载模块字节，替代真实 IPFS 拉取。
+// FetchModule 从磁盘加载模块字节，替代真实 IPFS 拉取；以 CID 命名的文件会校验内容。
+// 目录中存在 <cid>.car 时优先从 CAR 中校验并重建文件，"<cid>/路径" 形式的引用在 CAR 内按目录解析，
+// 否则读取镜像目录中已解包的 <cid>/路径 文件；不是 CID 的名称只能指向镜像目录内的文件，绝对路径与 ".." 被拒绝。
 func (p *PlaceholderClient) FetchModule(ctx context.Context, cid string) ([]byte, error) {
 	if p.ModuleDir == "" {
 		return nil, fmt.Errorf("module directory not configured")
@@ -31,11 +35,148 @@ func (p *PlaceholderClient) FetchModule(ctx context.Context, cid string) ([]byte
 	if cid == "" {
 		return nil, fmt.Errorf("empty cid")
 	}
-	path := filepath.Join(p.ModuleDir, cid)
+	root, segments, refErr := splitRef(cid)
+	if refErr == nil {
+		if data, ok, err := p.fetchCAR(ctx, root, segments); ok || err != nil {
+			return data, err
+		}
+	}
+	rel := filepath.FromSlash(cid)
+	if refErr == nil {
+		rel = filepath.FromSlash(refPath(root, segments))
+	} else if !filepath.IsLocal(rel) {
+		// 引用来自远程提交（HTTP/gRPC），不能让它读取镜像目录之外的文件。
+		return nil, fmt.Errorf("module ref %q is not a path inside the module directory: %w", cid, refErr)
+	}
+	path := filepath.Join(p.ModuleDir, rel)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read module %s: %w", path, err)
 	}
+	// 文件名是合法 CID 时校验内容，便于发现镜像目录中被替换或损坏的模块。
+	switch {
+	case refErr == nil && len(segments) == 0:
+		if err := verifyContent(root, data); err != nil {
+			return nil, fmt.Errorf("module %s: %w", path, err)
+		}
+	case refErr == nil:
+		p.log.Warnf("module %s is read from an unpacked directory, skipping integrity check", cid)
+	default:
+		p.log.Warnf("module name %s is not a cid, skipping integrity check", cid)
+	}
 	p.log.Infof("loaded wasm module %s (%d bytes)", cid, len(data))
 	return data, nil
 }
+
+// FetchDirectory 读取 ref 指向的目录：优先从 <cid>.car 中校验并展开，否则读取镜像目录中已解包的目录（不校验）。
+func (p *PlaceholderClient) FetchDirectory(ctx context.Context, ref string) (map[string][]byte, error) {
+	if p.ModuleDir == "" {
+		return nil, fmt.Errorf("module directory not configured")
+	}
+	root, segments, err := splitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	archive, path, err := p.openCAR(root)
+	if err != nil {
+		return nil, err
+	}
+	if archive != nil {
+		target, err := resolvePath(ctx, root, segments, archive.fetch)
+		if err != nil {
+			return nil, fmt.Errorf("car %s: %w", path, err)
+		}
+		files, err := fetchTree(ctx, target, archive.fetch)
+		if err != nil {
+			return nil, fmt.Errorf("car %s: %w", path, err)
+		}
+		p.log.Infof("loaded directory %s from car (%d files)", refPath(root, segments), len(files))
+		return files, nil
+	}
+
+	dir := filepath.Join(p.ModuleDir, filepath.FromSlash(refPath(root, segments)))
+	files, err := readDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	p.log.Warnf("directory %s is read from an unpacked directory, skipping integrity check", refPath(root, segments))
+	p.log.Infof("loaded directory %s (%d files)", refPath(root, segments), len(files))
+	return files, nil
+}
+
+// fetchCAR 读取镜像目录中的 <cid>.car 并重建 segments 指向的文件，ok=false 表示文件不存在。
+func (p *PlaceholderClient) fetchCAR(ctx context.Context, root cidpkg.Cid, segments []string) ([]byte, bool, error) {
+	archive, path, err := p.openCAR(root)
+	if err != nil || archive == nil {
+		return nil, err != nil, err
+	}
+	target, err := resolvePath(ctx, root, segments, archive.fetch)
+	if err != nil {
+		return nil, true, fmt.Errorf("car %s: %w", path, err)
+	}
+	data, blocks, err := archive.assemble(ctx, target)
+	if err != nil {
+		return nil, true, fmt.Errorf("car %s: %w", path, err)
+	}
+	p.log.Infof("loaded wasm module %s from car (%d bytes, %d verified blocks)", refPath(root, segments), len(data), blocks)
+	return data, true, nil
+}
+
+// openCAR 解析镜像目录中的 <cid>.car，文件不存在时返回 nil 归档。
+func (p *PlaceholderClient) openCAR(root cidpkg.Cid) (*carArchive, string, error) {
+	path := filepath.Join(p.ModuleDir, root.String()+".car")
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, path, nil
+		}
+		return nil, path, fmt.Errorf("read car %s: %w", path, err)
+	}
+	archive, err := parseCAR(payload)
+	if err != nil {
+		return nil, path, fmt.Errorf("car %s: %w", path, err)
+	}
+	return archive, path, nil
+}
+
+// readDirectory 递归读取本地目录中的普通文件，沿用与 IPFS 目录相同的数量与大小上限。
+func readDirectory(dir string) (map[string][]byte, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	files := map[string][]byte{}
+	var total int
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(files) >= maxDirectoryFiles {
+			return fmt.Errorf("directory has more than %d files", maxDirectoryFiles)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		total += len(data)
+		if total > maxModuleBytes {
+			return fmt.Errorf("directory larger than %d bytes", maxModuleBytes)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read directory %s: %w", dir, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,42 @@
+package ipfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"executor/internal/cid"
+)
+
+func TestPlaceholderRejectsRefsOutsideModuleDir(t *testing.T) {
+	root := t.TempDir()
+	mirror := filepath.Join(root, "mirror")
+	if err := os.MkdirAll(filepath.Join(mirror, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	c := cid.Sum(cid.Raw, module).String()
+	for name, data := range map[string][]byte{
+		filepath.Join(mirror, c):               module,
+		filepath.Join(mirror, "lib", "a.wasm"): module,
+		filepath.Join(root, "secret"):          []byte("secret"),
+	} {
+		if err := os.WriteFile(name, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := NewPlaceholderClient(mirror, testLogger{t})
+	ctx := context.Background()
+
+	for _, ref := range []string{c, "/ipfs/" + c, "lib/a.wasm"} {
+		if _, err := client.FetchModule(ctx, ref); err != nil {
+			t.Errorf("%s: %v", ref, err)
+		}
+	}
+	for _, ref := range []string{"../secret", "lib/../../secret", filepath.Join(root, "secret"), c + "/../../secret"} {
+		if data, err := client.FetchModule(ctx, ref); err == nil {
+			t.Errorf("%s: read %q outside the module directory", ref, data)
+		}
+	}
+}
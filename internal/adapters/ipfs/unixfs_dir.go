@@ -0,0 +1,184 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"executor/internal/cid"
+)
+
+// maxDirectoryFiles 限制单个数据目录包含的文件数量。
+const maxDirectoryFiles = 1024
+
+// splitRef 把 "<cid>/a/b"（可带 /ipfs/ 前缀与末尾斜杠）拆分为根 CID 与路径段。
+func splitRef(ref string) (cid.Cid, []string, error) {
+	ref = strings.TrimSpace(ref)
+	ref = strings.TrimPrefix(ref, "/ipfs/")
+	ref = strings.Trim(ref, "/")
+	if ref == "" {
+		return cid.Cid{}, nil, fmt.Errorf("cid is empty")
+	}
+	parts := strings.Split(ref, "/")
+	root, err := cid.Parse(parts[0])
+	if err != nil {
+		return cid.Cid{}, nil, err
+	}
+	var segments []string
+	for _, seg := range parts[1:] {
+		if seg == "" {
+			continue
+		}
+		if seg == "." || seg == ".." {
+			return cid.Cid{}, nil, fmt.Errorf("invalid path segment %q in %s", seg, ref)
+		}
+		segments = append(segments, seg)
+	}
+	return root, segments, nil
+}
+
+// refPath 返回用于网关 URL 的 "<cid>/a/b" 形式。
+func refPath(root cid.Cid, segments []string) string {
+	return strings.Join(append([]string{root.String()}, segments...), "/")
+}
+
+// resolvePath 沿 UnixFS 目录逐段解析路径，返回目标节点的 CID。每个目录块都经过校验。
+func resolvePath(ctx context.Context, root cid.Cid, segments []string, fetch blockFetcher) (cid.Cid, error) {
+	current := root
+	for i, seg := range segments {
+		links, err := directoryLinks(ctx, current, fetch)
+		if err != nil {
+			return cid.Cid{}, fmt.Errorf("resolve %s: %w", refPath(root, segments[:i+1]), err)
+		}
+		next, ok := findLink(links, seg)
+		if !ok {
+			return cid.Cid{}, fmt.Errorf("resolve %s: no such file or directory", refPath(root, segments[:i+1]))
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// directoryLinks 读取 UnixFS 目录节点的链接；HAMT 分片目录暂不支持。
+func directoryLinks(ctx context.Context, c cid.Cid, fetch blockFetcher) ([]pbLink, error) {
+	if c.Codec != cid.DagPB {
+		return nil, fmt.Errorf("%s is not a directory", c)
+	}
+	block, err := fetch(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	node, err := decodePBNode(block)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", c, err)
+	}
+	fs, err := decodeUnixFS(node.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", c, err)
+	}
+	switch fs.Type {
+	case unixfsDirectory:
+		return node.Links, nil
+	case unixfsHAMTShard:
+		return nil, fmt.Errorf("%s is a sharded directory, which is not supported", c)
+	default:
+		return nil, fmt.Errorf("%s is not a directory", c)
+	}
+}
+
+func findLink(links []pbLink, name string) (cid.Cid, bool) {
+	for _, link := range links {
+		if link.Name == name {
+			return link.Cid, true
+		}
+	}
+	return cid.Cid{}, false
+}
+
+// fetchTree 递归读取 UnixFS 目录，返回相对路径到文件内容的映射；所有块都经过校验。
+func fetchTree(ctx context.Context, root cid.Cid, fetch blockFetcher) (map[string][]byte, error) {
+	fetch = memoize(fetch)
+	files := map[string][]byte{}
+	var total int
+	var walk func(c cid.Cid, prefix string, depth int) error
+	walk = func(c cid.Cid, prefix string, depth int) error {
+		if depth > maxDAGDepth {
+			return fmt.Errorf("directory deeper than %d levels", maxDAGDepth)
+		}
+		links, err := directoryLinks(ctx, c, fetch)
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			if link.Name == "" || link.Name == "." || link.Name == ".." || strings.ContainsAny(link.Name, "/\\\x00") {
+				return fmt.Errorf("invalid entry name %q in %s", link.Name, c)
+			}
+			name := path.Join(prefix, link.Name)
+			isDir, err := isDirectory(ctx, link.Cid, fetch)
+			if err != nil {
+				return err
+			}
+			if isDir {
+				if err := walk(link.Cid, name, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			data, _, err := assembleFile(ctx, link.Cid, fetch)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			total += len(data)
+			if total > maxModuleBytes {
+				return fmt.Errorf("directory larger than %d bytes", maxModuleBytes)
+			}
+			if len(files) >= maxDirectoryFiles {
+				return fmt.Errorf("directory has more than %d files", maxDirectoryFiles)
+			}
+			files[name] = data
+		}
+		return nil
+	}
+	if err := walk(root, "", 0); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// isDirectory 判断节点是否为 UnixFS 目录（raw 块一定是文件）。
+func isDirectory(ctx context.Context, c cid.Cid, fetch blockFetcher) (bool, error) {
+	if c.Codec != cid.DagPB {
+		return false, nil
+	}
+	block, err := fetch(ctx, c)
+	if err != nil {
+		return false, err
+	}
+	node, err := decodePBNode(block)
+	if err != nil {
+		return false, fmt.Errorf("decode %s: %w", c, err)
+	}
+	fs, err := decodeUnixFS(node.Data)
+	if err != nil {
+		return false, fmt.Errorf("decode %s: %w", c, err)
+	}
+	return fs.Type == unixfsDirectory || fs.Type == unixfsHAMTShard, nil
+}
+
+// memoize 缓存一次目录遍历中已获取的块，避免判断节点类型后再次请求同一块。
+func memoize(fetch blockFetcher) blockFetcher {
+	seen := map[string][]byte{}
+	return func(ctx context.Context, c cid.Cid) ([]byte, error) {
+		key := string(c.Multihash())
+		if block, ok := seen[key]; ok {
+			return block, nil
+		}
+		block, err := fetch(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		seen[key] = block
+		return block, nil
+	}
+}
@@ -0,0 +1,197 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+
+	"executor/internal/cid"
+	"executor/internal/coordinator"
+)
+
+const maxDAGDepth = 32
+
+// blockFetcher 获取单个已按 CID 校验过的块。
+type blockFetcher func(ctx context.Context, c cid.Cid) ([]byte, error)
+
+// assembleFile 深度优先遍历 UnixFS 文件 DAG，逐块获取并按顺序拼接文件内容，返回内容与块数。
+func assembleFile(ctx context.Context, root cid.Cid, fetch blockFetcher) ([]byte, int, error) {
+	var (
+		out    []byte
+		blocks int
+		walk   func(c cid.Cid, depth int) error
+	)
+	walk = func(c cid.Cid, depth int) error {
+		if depth > maxDAGDepth {
+			return fmt.Errorf("dag deeper than %d levels", maxDAGDepth)
+		}
+		block, err := fetch(ctx, c)
+		if err != nil {
+			return err
+		}
+		blocks++
+
+		content, links, err := fileBlock(c, block)
+		if err != nil {
+			return err
+		}
+		out = append(out, content...)
+		if len(out) > maxModuleBytes {
+			return fmt.Errorf("module larger than %d bytes", maxModuleBytes)
+		}
+		for _, link := range links {
+			if err := walk(link.Cid, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root, 0); err != nil {
+		return nil, 0, err
+	}
+	return out, blocks, nil
+}
+
+// fileBlock 解析一个已校验的块：返回块内的文件数据与按顺序需要继续拉取的子块链接。
+func fileBlock(c cid.Cid, block []byte) ([]byte, []pbLink, error) {
+	switch c.Codec {
+	case cid.Raw:
+		return block, nil, nil
+	case cid.DagPB:
+		node, err := decodePBNode(block)
+		if err != nil {
+			return nil, nil, fmt.Errorf("decode %s: %w", c, err)
+		}
+		fs, err := decodeUnixFS(node.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("decode %s: %w", c, err)
+		}
+		if fs.Type != unixfsFile && fs.Type != unixfsRaw {
+			return nil, nil, fmt.Errorf("%s is not a unixfs file (type %d)", c, fs.Type)
+		}
+		return fs.Data, node.Links, nil
+	default:
+		return nil, nil, fmt.Errorf("%s: unsupported codec 0x%x", c, c.Codec)
+	}
+}
+
+// verifyContent 校验完整文件内容与 CID 一致：raw CID 直接比对哈希；dag-pb CID 按 ipfs add 的默认参数
+// （见 importFile）在本地重建文件 DAG 后比对根 CID，CIDv1 依次尝试 raw 叶子与 dag-pb 叶子。
+// 非默认分块或布局生成的 DAG 无法仅凭内容复算，视为校验失败，需要改为逐块获取（assembleFile）。
+func verifyContent(c cid.Cid, content []byte) error {
+	var err error
+	switch c.Codec {
+	case cid.Raw:
+		err = c.Verify(content)
+	case cid.DagPB:
+		err = verifyFileDAG(c, content)
+	default:
+		err = fmt.Errorf("%s: unsupported codec 0x%x", c, c.Codec)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", coordinator.ErrIntegrity, err)
+	}
+	return nil
+}
+
+func verifyFileDAG(c cid.Cid, content []byte) error {
+	if c.HashCode != cid.SHA2_256 {
+		return fmt.Errorf("cid %s: unsupported multihash 0x%x", c, c.HashCode)
+	}
+	layouts := []bool{false}
+	if c.Version == 1 {
+		layouts = []bool{true, false}
+	}
+	for _, rawLeaves := range layouts {
+		if importFile(content, c.Version, rawLeaves).Equals(c) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s (file dag rebuilt with default chunking differs)", cid.ErrMismatch, c)
+}
+
+// ipfs add 的默认分块大小与 balanced 布局中每个节点的链接上限。
+const (
+	defaultChunkSize = 256 << 10
+	defaultMaxLinks  = 174
+)
+
+// dagNode 是重建过程中的一个节点：CID、其下的文件字节数与序列化后整棵子树的大小（链接的 Tsize）。
+type dagNode struct {
+	cid      cid.Cid
+	fileSize uint64
+	tsize    uint64
+}
+
+// dagBuilder 按 go-unixfs 的 balanced 布局把内容切成定长块并逐层建立父节点。
+type dagBuilder struct {
+	data      []byte
+	version   int
+	rawLeaves bool
+}
+
+// importFile 按 ipfs add 的默认参数（256KiB 定长分块、balanced 布局、每节点至多 174 个链接、sha2-256）
+// 重建文件 DAG 并返回根 CID。rawLeaves 为 true 时叶子是 raw 块，否则是 UnixFS 文件节点。
+func importFile(content []byte, version int, rawLeaves bool) cid.Cid {
+	b := &dagBuilder{data: content, version: version, rawLeaves: rawLeaves}
+	root := b.leaf()
+	for depth := 1; len(b.data) > 0; depth++ {
+		root = b.fill(&root, depth)
+	}
+	return root.cid
+}
+
+func (b *dagBuilder) leaf() dagNode {
+	n := min(len(b.data), defaultChunkSize)
+	chunk := b.data[:n]
+	b.data = b.data[n:]
+	if b.rawLeaves {
+		return dagNode{cid: cid.Sum(cid.Raw, chunk), fileSize: uint64(n), tsize: uint64(n)}
+	}
+	block := encodeUnixFSFile(chunk)
+	return dagNode{cid: b.sum(block), fileSize: uint64(n), tsize: uint64(len(block))}
+}
+
+// fill 建立深度为 depth 的父节点，first 非空时作为第一个子节点（即上一轮的根）。
+func (b *dagBuilder) fill(first *dagNode, depth int) dagNode {
+	var children []dagNode
+	if first != nil {
+		children = append(children, *first)
+	}
+	for len(children) < defaultMaxLinks && len(b.data) > 0 {
+		if depth == 1 {
+			children = append(children, b.leaf())
+		} else {
+			children = append(children, b.fill(nil, depth-1))
+		}
+	}
+
+	var fs []byte
+	var total uint64
+	for _, child := range children {
+		total += child.fileSize
+	}
+	fs = appendProtoVarint(fs, 1, unixfsFile)
+	fs = appendProtoVarint(fs, 3, total)
+	for _, child := range children {
+		fs = appendProtoVarint(fs, 4, child.fileSize)
+	}
+	// dag-pb 规范编码：链接（字段 2）在前、Data（字段 1）在后；链接名为空字符串但仍写出。
+	var block []byte
+	tsize := uint64(0)
+	for _, child := range children {
+		var link []byte
+		link = appendProtoBytes(link, 1, child.cid.Bytes())
+		link = appendProtoBytes(link, 2, nil)
+		link = appendProtoVarint(link, 3, child.tsize)
+		block = appendProtoBytes(block, 2, link)
+		tsize += child.tsize
+	}
+	block = appendProtoBytes(block, 1, fs)
+	return dagNode{cid: b.sum(block), fileSize: total, tsize: tsize + uint64(len(block))}
+}
+
+func (b *dagBuilder) sum(block []byte) cid.Cid {
+	c := cid.Sum(cid.DagPB, block)
+	c.Version = b.version
+	return c
+}
@@ -0,0 +1,46 @@
+package ipfs
+
+import (
+	"errors"
+	"testing"
+
+	"executor/internal/cid"
+	"executor/internal/coordinator"
+)
+
+func patternBytes(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+// TestVerifyContentMultiBlock 使用 ipfs add 默认参数（kubo/boxo balanced 布局）生成的 CID。
+func TestVerifyContentMultiBlock(t *testing.T) {
+	cases := []struct {
+		size int
+		cid  string
+	}{
+		{600000, "QmWKdZuiD9zqoZFnLYbpV2Q5YhRCJWpqiVeYA8ygYEjcEe"},
+		{600000, "bafybeicp64het67shnhxiyl3sg5mylxqop6pnqsqpfecb6pmni2ghoxzom"},
+		{600000, "bafybeihgfgmasdafefgp5wfkkr5pfqjz25g65ecqjsl5k6ojf5gfkclsna"},
+		// 超过 174 个叶子，需要两层内部节点。
+		{174*262144 + 1, "QmTedsTekQQkgACJXb1sPZSW8bLdS9LPMrT7L4YdjNRd4n"},
+		{174*262144 + 1, "bafybeib4y7ghw2rq7bracc4xwtxrbzo7cfvagdpte2tmrkgwl6dyard3cm"},
+	}
+	for _, tc := range cases {
+		c, err := cid.Parse(tc.cid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := patternBytes(tc.size)
+		if err := verifyContent(c, data); err != nil {
+			t.Errorf("%s: %v", tc.cid, err)
+		}
+		data[len(data)-1] ^= 1
+		if err := verifyContent(c, data); !errors.Is(err, coordinator.ErrIntegrity) {
+			t.Errorf("%s: tampered content: err = %v, want ErrIntegrity", tc.cid, err)
+		}
+	}
+}
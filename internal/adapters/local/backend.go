@@ -0,0 +1,191 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"executor/internal/coordinator"
+	"executor/internal/wasmexec"
+)
+
+// Backend 在协调器进程内用 wazero 直接运行模块，无需 Kubernetes，适合本地调试与 CI。
+type Backend struct {
+	mu   sync.Mutex
+	runs map[string]*run
+	log  coordinator.Logger
+}
+
+// run 保存一次进程内运行的状态，done 关闭后其余字段只读。
+type run struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	status coordinator.ExecutionStatus
+	logs   bytes.Buffer
+}
+
+// NewBackend 构造进程内执行后端。
+func NewBackend(log coordinator.Logger) *Backend {
+	return &Backend{
+		runs: map[string]*run{},
+		log:  log,
+	}
+}
+
+// Submit 在后台 goroutine 中运行模块，任务参数按执行器的环境变量语义解析。
+func (b *Backend) Submit(ctx context.Context, cfg coordinator.Config, task coordinator.TaskRequest, module []byte) (coordinator.Execution, error) {
+	env := map[string]string{"ENTRY": task.Entry}
+	for k, v := range task.Args {
+		env[k] = v
+	}
+	getenv := func(key string) string {
+		return strings.TrimSpace(env[key])
+	}
+	inv, err := wasmexec.ResolveInvocation(task.InputJSON, getenv)
+	if err != nil {
+		return coordinator.Execution{}, fmt.Errorf("resolve invocation: %w", err)
+	}
+	limits, err := wasmexec.ParseLimits(getenv)
+	if err != nil {
+		return coordinator.Execution{}, fmt.Errorf("resolve limits: %w", err)
+	}
+
+	dataDir, err := writeDataDir(task.DataFiles)
+	if err != nil {
+		return coordinator.Execution{}, err
+	}
+
+	exec := coordinator.Execution{TaskID: task.TaskID, Name: "local-" + task.TaskID}
+	// 运行脱离提交请求的生命周期，但保留任务截止时间。
+	var (
+		runCtx context.Context
+		cancel context.CancelFunc
+	)
+	if deadline, ok := ctx.Deadline(); ok {
+		runCtx, cancel = context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	} else {
+		runCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+	}
+	r := &run{cancel: cancel, done: make(chan struct{})}
+
+	b.mu.Lock()
+	if _, exists := b.runs[exec.Name]; exists {
+		b.mu.Unlock()
+		cancel()
+		removeDataDir(dataDir)
+		return coordinator.Execution{}, fmt.Errorf("execution %s already exists", exec.Name)
+	}
+	b.runs[exec.Name] = r
+	b.mu.Unlock()
+
+	b.log.Infof("task %s: running %s in-process", task.TaskID, inv.Entry)
+	go func() {
+		defer close(r.done)
+		defer removeDataDir(dataDir)
+		out, err := wasmexec.Run(runCtx, module, inv, wasmexec.Options{Stdout: &r.logs, Stderr: &r.logs, Limits: limits, DataDir: dataDir})
+		if err != nil {
+			r.status.Message = err.Error()
+			fmt.Fprintln(&r.logs, err)
+			if payload, merr := json.Marshal(wasmexec.Failure(inv.Entry, err)); merr == nil {
+				r.status.Result = payload
+			}
+			return
+		}
+		payload, err := json.Marshal(out)
+		if err != nil {
+			r.status.Message = fmt.Sprintf("encode result: %v", err)
+			return
+		}
+		fmt.Fprintln(&r.logs, string(payload))
+		r.status.Succeeded = true
+		r.status.Result = payload
+	}()
+	return exec, nil
+}
+
+// Wait 阻塞直到运行结束或上下文取消。
+func (b *Backend) Wait(ctx context.Context, exec coordinator.Execution) (coordinator.ExecutionStatus, error) {
+	r, err := b.lookup(exec)
+	if err != nil {
+		return coordinator.ExecutionStatus{}, err
+	}
+	select {
+	case <-ctx.Done():
+		return coordinator.ExecutionStatus{}, ctx.Err()
+	case <-r.done:
+		return r.status, nil
+	}
+}
+
+// Logs 返回模块的 stdout/stderr 以及结果 JSON。
+func (b *Backend) Logs(ctx context.Context, exec coordinator.Execution) (string, error) {
+	r, err := b.lookup(exec)
+	if err != nil {
+		return "", err
+	}
+	select {
+	case <-r.done:
+		return r.logs.String(), nil
+	default:
+		return "", fmt.Errorf("execution %s still running", exec.Name)
+	}
+}
+
+// Cleanup 取消仍在运行的模块并释放运行记录。
+func (b *Backend) Cleanup(ctx context.Context, exec coordinator.Execution) {
+	b.mu.Lock()
+	r, ok := b.runs[exec.Name]
+	delete(b.runs, exec.Name)
+	b.mu.Unlock()
+	if ok {
+		r.cancel()
+	}
+}
+
+func (b *Backend) lookup(exec coordinator.Execution) (*run, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, ok := b.runs[exec.Name]
+	if !ok {
+		return nil, fmt.Errorf("execution %s not found", exec.Name)
+	}
+	return r, nil
+}
+
+// writeDataDir 把任务数据目录写入临时目录供 wazero 只读挂载，没有数据时返回空路径。
+func writeDataDir(files map[string][]byte) (string, error) {
+	if len(files) == 0 {
+		return "", nil
+	}
+	dir, err := os.MkdirTemp("", "wasm-data-")
+	if err != nil {
+		return "", fmt.Errorf("create data dir: %w", err)
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("invalid data path %q", name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("write data dir: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0o444); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("write data dir: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+func removeDataDir(dir string) {
+	if dir != "" {
+		os.RemoveAll(dir)
+	}
+}
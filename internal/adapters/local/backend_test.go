@@ -0,0 +1,129 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"executor/internal/coordinator"
+)
+
+// testLogger 把协调器与后端日志写入测试输出。
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Infof(format string, args ...any)  { l.t.Logf("[INFO] "+format, args...) }
+func (l testLogger) Warnf(format string, args ...any)  { l.t.Logf("[WARN] "+format, args...) }
+func (l testLogger) Errorf(format string, args ...any) { l.t.Logf("[ERROR] "+format, args...) }
+
+// arithModule 导出 add(i32, i32) -> i32 与执行 unreachable 的 trap()。
+var arithModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// type: (i32, i32) -> i32, () -> ()
+	0x01, 0x0a, 0x02, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f, 0x60, 0x00, 0x00,
+	// function: add, trap
+	0x03, 0x03, 0x02, 0x00, 0x01,
+	// export: add, trap
+	0x07, 0x0e, 0x02,
+	0x03, 'a', 'd', 'd', 0x00, 0x00,
+	0x04, 't', 'r', 'a', 'p', 0x00, 0x01,
+	// code: add = local.get 0 + local.get 1, trap = unreachable
+	0x0a, 0x0d, 0x02, 0x07, 0x00, 0x20, 0x00, 0x20, 0x01, 0x6a, 0x0b, 0x03, 0x00, 0x00, 0x0b,
+}
+
+// staticTasks 是依次投递固定任务、随后结束订阅的任务来源，记录确认与发布的结果。
+type staticTasks struct {
+	tasks []coordinator.TaskRequest
+
+	mu      sync.Mutex
+	acked   []string
+	results map[string]coordinator.TaskResult
+}
+
+func (s *staticTasks) SubscribeTasks(ctx context.Context, out chan<- coordinator.TaskRequest) error {
+	for _, task := range s.tasks {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- task:
+		}
+	}
+	return nil
+}
+
+func (s *staticTasks) AckTask(ctx context.Context, taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acked = append(s.acked, taskID)
+	return nil
+}
+
+func (s *staticTasks) PublishResult(ctx context.Context, result coordinator.TaskResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[result.TaskID] = result
+	return nil
+}
+
+// staticModules 按 CID 返回内存中的内容。
+type staticModules map[string][]byte
+
+func (m staticModules) FetchModule(ctx context.Context, cid string) ([]byte, error) {
+	if data, ok := m[cid]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("cid %s not found", cid)
+}
+
+// TestCoordinatorEndToEnd 在进程内后端上运行完整流程：订阅、确认、拉取、执行、发布。
+func TestCoordinatorEndToEnd(t *testing.T) {
+	source := &staticTasks{
+		tasks: []coordinator.TaskRequest{
+			{TaskID: "sum", WasmCID: "mod", InputJSON: []byte(`{"entry":"add","args":[2,3]}`)},
+			{TaskID: "sum-input", WasmCID: "mod", InputCID: "input"},
+			{TaskID: "trap", WasmCID: "mod", Entry: "trap"},
+			{TaskID: "missing", WasmCID: "absent"},
+		},
+		results: map[string]coordinator.TaskResult{},
+	}
+	ipfs := staticModules{
+		"mod":   arithModule,
+		"input": []byte(`{"entry":"add","args":[40,2]}`),
+	}
+	log := testLogger{t}
+	coord, err := coordinator.NewCoordinator(coordinator.Config{
+		Log:   log,
+		Retry: coordinator.RetryPolicy{MaxAttempts: 1},
+	}, source, ipfs, NewBackend(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := coord.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if len(source.results) != len(source.tasks) {
+		t.Fatalf("published %d results, want %d", len(source.results), len(source.tasks))
+	}
+	for id, want := range map[string]string{"sum": "5", "sum-input": "42"} {
+		res := source.results[id]
+		if !res.Success || res.Entry != "add" || len(res.Results) != 1 || string(res.Results[0].Value) != want {
+			t.Errorf("%s: success=%v entry=%q results=%v err=%v", id, res.Success, res.Entry, res.Results, res.Error)
+		}
+	}
+	if res := source.results["trap"]; res.Success || res.FailureReason != coordinator.FailureExecution {
+		t.Errorf("trap: success=%v reason=%q err=%v", res.Success, res.FailureReason, res.Error)
+	}
+	if res := source.results["missing"]; res.Success || res.FailureReason != coordinator.FailureError {
+		t.Errorf("missing: success=%v reason=%q err=%v", res.Success, res.FailureReason, res.Error)
+	}
+	if len(source.acked) != len(source.tasks) {
+		t.Errorf("acked %v", source.acked)
+	}
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"executor/internal/coordinator"
+)
+
+const recordSuffix = ".json"
+
+// FileStore 把每个任务记录保存为目录下的独立 JSON 文件，适合单副本协调器的嵌入式持久化。
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+	log coordinator.Logger
+}
+
+// NewFileStore 创建（必要时初始化）基于目录的任务状态存储。
+func NewFileStore(dir string, log coordinator.Logger) (*FileStore, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("task store directory is empty")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create task store dir: %w", err)
+	}
+	s := &FileStore{dir: dir, log: log}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get 读取指定任务的记录，文件不存在时返回 found=false。
+func (s *FileStore) Get(ctx context.Context, taskID string) (coordinator.TaskRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rec coordinator.TaskRecord
+	data, err := os.ReadFile(s.path(taskID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return rec, false, nil
+		}
+		return rec, false, fmt.Errorf("read task record %s: %w", taskID, err)
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, false, fmt.Errorf("decode task record %s: %w", taskID, err)
+	}
+	return rec, true, nil
+}
+
+// Put 先写临时文件并 fsync，再原子重命名覆盖旧记录，避免崩溃时留下半截文件。
+func (s *FileStore) Put(ctx context.Context, rec coordinator.TaskRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode task record %s: %w", rec.Task.TaskID, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(s.dir, ".record-*")
+	if err != nil {
+		return fmt.Errorf("create temp record: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp record: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temp record: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp record: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(rec.Task.TaskID)); err != nil {
+		return fmt.Errorf("commit task record %s: %w", rec.Task.TaskID, err)
+	}
+	return nil
+}
+
+// List 返回目录中全部可解析的记录（按更新时间排序），损坏的文件仅记录警告。
+func (s *FileStore) List(ctx context.Context) ([]coordinator.TaskRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []coordinator.TaskRecord
+	err := s.scan(func(name string, rec coordinator.TaskRecord) error {
+		out = append(out, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.Before(out[j].UpdatedAt) })
+	return out, nil
+}
+
+// Prune 删除 UpdatedAt 早于 before 的已发布记录。
+func (s *FileStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	err := s.scan(func(name string, rec coordinator.TaskRecord) error {
+		if rec.Phase != coordinator.PhasePublished || !rec.UpdatedAt.Before(before) {
+			return nil
+		}
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+			return fmt.Errorf("remove task record %s: %w", rec.Task.TaskID, err)
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// scan 依次解码目录中的记录文件并交给 fn，无法读取或解码的文件记录警告后跳过。调用方须持有 s.mu。
+func (s *FileStore) scan(fn func(name string, rec coordinator.TaskRecord) error) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("list task store: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, recordSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			s.log.Warnf("read task record %s: %v", name, err)
+			continue
+		}
+		var rec coordinator.TaskRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			s.log.Warnf("decode task record %s: %v", name, err)
+			continue
+		}
+		if err := fn(name, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrate 把旧版以 base64 编码 TaskID 命名的记录文件重命名为哈希文件名。
+func (s *FileStore) migrate() error {
+	return s.scan(func(name string, rec coordinator.TaskRecord) error {
+		want := s.path(rec.Task.TaskID)
+		if filepath.Join(s.dir, name) == want {
+			return nil
+		}
+		if err := os.Rename(filepath.Join(s.dir, name), want); err != nil {
+			return fmt.Errorf("migrate task record %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// path 以 TaskID 的 SHA-256 十六进制命名记录文件，文件名长度固定，不受 TaskID 字符与长度影响。
+func (s *FileStore) path(taskID string) string {
+	sum := sha256.Sum256([]byte(taskID))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+recordSuffix)
+}
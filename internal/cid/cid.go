@@ -0,0 +1,188 @@
+// Package cid 解析并校验 IPFS 内容标识符（CIDv0/CIDv1），只实现协调器需要的编解码与哈希。
+package cid
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 常用的 multicodec 编号。
+const (
+	Raw   uint64 = 0x55
+	DagPB uint64 = 0x70
+
+	// Identity 表示内容直接内联在 multihash 中。
+	Identity uint64 = 0x00
+	SHA2_256 uint64 = 0x12
+)
+
+// ErrMismatch 表示内容哈希与 CID 不一致。
+var ErrMismatch = errors.New("content does not match cid")
+
+// Cid 是解析后的内容标识符。
+type Cid struct {
+	Version  int
+	Codec    uint64
+	HashCode uint64
+	Digest   []byte
+}
+
+// Parse 解析字符串形式的 CID：CIDv0 为 46 位 base58btc（Qm...），
+// CIDv1 支持 multibase 前缀 b（base32）、z（base58btc）与 f（base16）。
+func Parse(s string) (Cid, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return Cid{}, fmt.Errorf("invalid cid %q", s)
+	}
+	if len(s) == 46 && strings.HasPrefix(s, "Qm") {
+		mh, err := decodeBase58(s)
+		if err != nil {
+			return Cid{}, fmt.Errorf("invalid cid %q: %w", s, err)
+		}
+		c, err := fromMultihash(mh)
+		if err != nil {
+			return Cid{}, fmt.Errorf("invalid cid %q: %w", s, err)
+		}
+		return c, nil
+	}
+
+	var (
+		raw []byte
+		err error
+	)
+	switch s[0] {
+	case 'b', 'B':
+		raw, err = decodeBase32(strings.ToLower(s[1:]))
+	case 'z':
+		raw, err = decodeBase58(s[1:])
+	case 'f', 'F':
+		raw, err = decodeBase16(strings.ToLower(s[1:]))
+	default:
+		return Cid{}, fmt.Errorf("invalid cid %q: unsupported multibase prefix %q", s, s[0])
+	}
+	if err != nil {
+		return Cid{}, fmt.Errorf("invalid cid %q: %w", s, err)
+	}
+	c, n, err := Decode(raw)
+	if err != nil {
+		return Cid{}, fmt.Errorf("invalid cid %q: %w", s, err)
+	}
+	if n != len(raw) {
+		return Cid{}, fmt.Errorf("invalid cid %q: trailing bytes", s)
+	}
+	return c, nil
+}
+
+// Decode 从二进制形式解析 CID，返回消耗的字节数，供 dag-pb 链接与 CAR 使用。
+func Decode(b []byte) (Cid, int, error) {
+	// CIDv0 的二进制形式就是 sha2-256 multihash。
+	if len(b) >= 2 && b[0] == byte(SHA2_256) && b[1] == 32 {
+		if len(b) < 34 {
+			return Cid{}, 0, errors.New("truncated multihash")
+		}
+		c, err := fromMultihash(b[:34])
+		return c, 34, err
+	}
+	version, n1 := binary.Uvarint(b)
+	if n1 <= 0 {
+		return Cid{}, 0, errors.New("bad version varint")
+	}
+	if version != 1 {
+		return Cid{}, 0, fmt.Errorf("unsupported cid version %d", version)
+	}
+	codec, n2 := binary.Uvarint(b[n1:])
+	if n2 <= 0 {
+		return Cid{}, 0, errors.New("bad codec varint")
+	}
+	code, digest, n3, err := decodeMultihash(b[n1+n2:])
+	if err != nil {
+		return Cid{}, 0, err
+	}
+	return Cid{Version: 1, Codec: codec, HashCode: code, Digest: digest}, n1 + n2 + n3, nil
+}
+
+// Sum 计算 data 的 sha2-256 CIDv1。
+func Sum(codec uint64, data []byte) Cid {
+	sum := sha256.Sum256(data)
+	return Cid{Version: 1, Codec: codec, HashCode: SHA2_256, Digest: sum[:]}
+}
+
+// Verify 校验 block 的哈希与 CID 一致，失败时返回包装 ErrMismatch 的错误。
+func (c Cid) Verify(block []byte) error {
+	switch c.HashCode {
+	case SHA2_256:
+		sum := sha256.Sum256(block)
+		if !bytes.Equal(sum[:], c.Digest) {
+			return fmt.Errorf("%w: %s (sha2-256 %x)", ErrMismatch, c, sum[:])
+		}
+	case Identity:
+		if !bytes.Equal(block, c.Digest) {
+			return fmt.Errorf("%w: %s (identity)", ErrMismatch, c)
+		}
+	default:
+		return fmt.Errorf("cid %s: unsupported multihash 0x%x", c, c.HashCode)
+	}
+	return nil
+}
+
+// Multihash 返回 CID 的 multihash 二进制形式。
+func (c Cid) Multihash() []byte {
+	out := binary.AppendUvarint(nil, c.HashCode)
+	out = binary.AppendUvarint(out, uint64(len(c.Digest)))
+	return append(out, c.Digest...)
+}
+
+// Bytes 返回 CID 的二进制形式。
+func (c Cid) Bytes() []byte {
+	if c.Version == 0 {
+		return c.Multihash()
+	}
+	out := binary.AppendUvarint(nil, 1)
+	out = binary.AppendUvarint(out, c.Codec)
+	return append(out, c.Multihash()...)
+}
+
+// String 返回规范字符串：CIDv0 为 base58btc，CIDv1 为 base32 小写。
+func (c Cid) String() string {
+	if c.Version == 0 {
+		return encodeBase58(c.Multihash())
+	}
+	return "b" + encodeBase32(c.Bytes())
+}
+
+// Equals 比较两个 CID 是否标识同一内容编码。
+func (c Cid) Equals(o Cid) bool {
+	return c.Version == o.Version && c.Codec == o.Codec && c.HashCode == o.HashCode && bytes.Equal(c.Digest, o.Digest)
+}
+
+func fromMultihash(mh []byte) (Cid, error) {
+	code, digest, n, err := decodeMultihash(mh)
+	if err != nil {
+		return Cid{}, err
+	}
+	if n != len(mh) || code != SHA2_256 || len(digest) != 32 {
+		return Cid{}, errors.New("cidv0 requires a sha2-256 multihash")
+	}
+	return Cid{Version: 0, Codec: DagPB, HashCode: code, Digest: digest}, nil
+}
+
+func decodeMultihash(b []byte) (uint64, []byte, int, error) {
+	code, n1 := binary.Uvarint(b)
+	if n1 <= 0 {
+		return 0, nil, 0, errors.New("bad multihash code")
+	}
+	size, n2 := binary.Uvarint(b[n1:])
+	if n2 <= 0 {
+		return 0, nil, 0, errors.New("bad multihash length")
+	}
+	start := n1 + n2
+	if uint64(len(b)-start) < size {
+		return 0, nil, 0, errors.New("truncated multihash")
+	}
+	end := start + int(size)
+	return code, append([]byte(nil), b[start:end]...), end, nil
+}
@@ -0,0 +1,77 @@
+package cid
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base32Lower = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+func decodeBase32(s string) ([]byte, error) {
+	return base32Lower.DecodeString(s)
+}
+
+func encodeBase32(b []byte) string {
+	return base32Lower.EncodeToString(b)
+}
+
+func decodeBase16(s string) ([]byte, error) {
+	return hex.DecodeString(s)
+}
+
+// decodeBase58 解码 base58btc；CID 很短，直接使用大整数换算。
+func decodeBase58(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty base58 string")
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	for i := 0; i < len(s); i++ {
+		idx := indexBase58(s[i])
+		if idx < 0 {
+			return nil, errors.New("invalid base58 character")
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(idx)))
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+func encodeBase58(b []byte) string {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func indexBase58(c byte) int {
+	for i := 0; i < len(base58Alphabet); i++ {
+		if base58Alphabet[i] == c {
+			return i
+		}
+	}
+	return -1
+}
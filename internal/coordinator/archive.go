@@ -0,0 +1,122 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	stageArchive = "archive"
+	// archiveErrorKey 是归档失败时写入 TaskResult.Metadata 的键。
+	archiveErrorKey = "archive.error"
+
+	resultBundleVersion = 1
+	resultBundleName    = "result.json"
+)
+
+// resultBundle 是归档到 IPFS 的规范化结果。字段顺序固定、时间统一为 UTC、map 按键排序，
+// 相同的结果总是得到相同的字节与 CID。
+type resultBundle struct {
+	Version       int               `json:"version"`
+	TaskID        string            `json:"task_id"`
+	ModuleCID     string            `json:"module_cid"`
+	InputCID      string            `json:"input_cid,omitempty"`
+	Entry         string            `json:"entry,omitempty"`
+	Success       bool              `json:"success"`
+	FailureReason FailureReason     `json:"failure_reason,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	Result        json.RawMessage   `json:"result,omitempty"`
+	Logs          string            `json:"logs,omitempty"`
+	Timings       bundleTimings     `json:"timings"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+type bundleTimings struct {
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// buildResultBundle 把任务请求与结果整理为归档内容。
+func buildResultBundle(task TaskRequest, result TaskResult) ([]byte, error) {
+	bundle := resultBundle{
+		Version:       resultBundleVersion,
+		TaskID:        result.TaskID,
+		ModuleCID:     task.WasmCID,
+		InputCID:      task.InputCID,
+		Entry:         result.Entry,
+		Success:       result.Success,
+		FailureReason: result.FailureReason,
+		Logs:          result.Logs,
+		Timings: bundleTimings{
+			StartedAt:  result.StartedAt.UTC(),
+			FinishedAt: result.FinishedAt.UTC(),
+		},
+		Metadata: result.Metadata,
+	}
+	if bundle.Entry == "" {
+		bundle.Entry = task.Entry
+	}
+	if result.Error != nil {
+		bundle.Error = result.Error.Error()
+	}
+	// OutputValue 保存执行器 result.json 原文，是合法 JSON 时原样嵌入。
+	if raw := []byte(result.OutputValue); json.Valid(raw) {
+		bundle.Result = raw
+	}
+	if !result.StartedAt.IsZero() {
+		bundle.Timings.DurationMS = result.FinishedAt.Sub(result.StartedAt).Milliseconds()
+	}
+	return json.Marshal(bundle)
+}
+
+// archiveResult 把结果归档到 IPFS 并写入 ResultCID；归档失败只记录警告，结果照常发布。
+// 归档的尝试次数（attempts.archive）与失败原因（archive.error）在打包之后才确定，
+// 因此只写入发布的结果，不进入结果包本身。
+func (c *Coordinator) archiveResult(ctx context.Context, rec *TaskRecord, result *TaskResult) {
+	if c.adder == nil {
+		return
+	}
+	bundle, err := buildResultBundle(rec.Task, *result)
+	if err != nil {
+		c.log.Warnf("task %s: encode result bundle: %v", rec.Task.TaskID, err)
+		setMetadata(result, archiveErrorKey, fmt.Sprintf("encode result bundle: %v", err))
+		return
+	}
+	var resultCID string
+	err = c.retry(ctx, rec, stageArchive, func(ctx context.Context) (err error) {
+		resultCID, err = c.adder.Add(ctx, resultBundleName, bundle)
+		return err
+	})
+	setMetadata(result, "attempts."+stageArchive, strconv.Itoa(rec.Attempts[stageArchive]))
+	if err != nil {
+		c.log.Warnf("task %s: archive result: %v", rec.Task.TaskID, err)
+		setMetadata(result, archiveErrorKey, err.Error())
+		return
+	}
+	result.ResultCID = resultCID
+	c.log.Infof("task %s: result archived as %s (%d bytes)", rec.Task.TaskID, resultCID, len(bundle))
+}
+
+// setMetadata 在结果的 Metadata 中写入一项，必要时创建 map。
+func setMetadata(result *TaskResult, key, value string) {
+	if result.Metadata == nil {
+		result.Metadata = map[string]string{}
+	}
+	result.Metadata[key] = value
+}
+
+// resolveArchiver 在启用结果归档时沿 Unwrap 链查找 IPFSAdder。
+func resolveArchiver(cfg Config, ipfs IPFSClient) (IPFSAdder, error) {
+	if !cfg.ArchiveResults {
+		return nil, nil
+	}
+	adder, ok := findIPFS[IPFSAdder](ipfs)
+	if !ok {
+		return nil, fmt.Errorf("result archival requires an ipfs client that can add content")
+	}
+	return adder, nil
+}
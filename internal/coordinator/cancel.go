@@ -0,0 +1,109 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// cancelMemory 是尚未开始处理的任务的取消请求保留时长，超时未出现的 TaskID 被遗忘。
+const cancelMemory = time.Hour
+
+// errTaskCancelled 是任务被任务来源撤回时的取消原因，用于与协调器退出、截止时间区分。
+var errTaskCancelled = errors.New("task cancelled")
+
+// inflight 记录正在处理的任务（同一 TaskID 同时只处理一次）及其取消函数，
+// 以及到达时任务尚未开始处理的取消请求。
+type inflight struct {
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc
+	pending map[string]time.Time
+}
+
+func newInflight() *inflight {
+	return &inflight{
+		running: map[string]context.CancelCauseFunc{},
+		pending: map[string]time.Time{},
+	}
+}
+
+// claim 登记任务开始处理，同一 TaskID 已在处理中时返回 false。处理结束后须调用 release。
+func (f *inflight) claim(taskID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.running[taskID]; ok {
+		return false
+	}
+	f.running[taskID] = nil
+	return true
+}
+
+// release 注销 claim 登记的任务。
+func (f *inflight) release(taskID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.running, taskID)
+}
+
+// track 为已登记的任务派生可被撤回的上下文；之前已收到该任务的取消请求时上下文立即取消。
+func (f *inflight) track(ctx context.Context, taskID string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running[taskID] = cancel
+	if _, ok := f.pending[taskID]; ok {
+		delete(f.pending, taskID)
+		cancel(errTaskCancelled)
+	}
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// cancel 撤回任务，返回任务是否正在处理；尚未开始处理的任务记为待取消，开始处理时立即中止。
+func (f *inflight) cancel(taskID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if cancel := f.running[taskID]; cancel != nil {
+		cancel(errTaskCancelled)
+		return true
+	}
+	now := time.Now()
+	for id, at := range f.pending {
+		if now.Sub(at) > cancelMemory {
+			delete(f.pending, id)
+		}
+	}
+	f.pending[taskID] = now
+	return false
+}
+
+// subscribeCancellations 在任务来源实现 TaskCanceller 时接收取消请求并中止对应任务。
+func (c *Coordinator) subscribeCancellations(ctx context.Context) {
+	canceller, ok := c.contract.(TaskCanceller)
+	if !ok {
+		return
+	}
+	ids := make(chan string)
+	go func() {
+		if err := canceller.SubscribeCancellations(ctx, ids); err != nil && ctx.Err() == nil {
+			c.log.Errorf("cancellation subscription failed: %v", err)
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-ids:
+			if c.inflight.cancel(id) {
+				c.log.Infof("task %s: cancelled by task source", id)
+			} else {
+				c.log.Infof("task %s: not in progress, cancellation recorded", id)
+			}
+		}
+	}
+}
+
+// cancelled 判断上下文是否因任务被撤回而结束。
+func cancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errTaskCancelled)
+}
@@ -1,11 +1,34 @@
 package coordinator
 
+import "time"
+
 // Config 描述协调器运行所需的最小配置信息。
 type Config struct {
 	Namespace     string
 	ExecutorImage string
 	JobTemplate   string
 	Log           Logger
+	// MaxConcurrentTasks 限制同时处理的任务数量，worker 全忙时订阅通道产生背压。
+	MaxConcurrentTasks int
+	// Store 持久化任务阶段，用于重启后恢复；为空时使用进程内存储。
+	Store TaskStore
+	// ModuleDelivery 选择模块投递方式：auto、configmap、chunked 或 fetch，见 Delivery* 常量。
+	ModuleDelivery string
+	// ConfigMapLimit 是单个 ConfigMap 承载模块的字节上限，auto 模式据此决定是否切换投递方式。
+	ConfigMapLimit int
+	// PodGatewayURL 是 Pod 内可访问的 IPFS 网关根地址，fetch 模式的 init 容器从这里下载模块。
+	PodGatewayURL string
+	// FetchImage 是 fetch 模式 init 容器使用的镜像，需要提供 wget 与 sha256sum。
+	FetchImage string
+	// DefaultTaskTimeout 是任务未指定 Timeout/Deadline 时的处理时限，0 表示不限制。
+	DefaultTaskTimeout time.Duration
+	// Retry 控制拉取、提交与等待阶段遇到暂时性错误时的重试，零值字段使用默认值。
+	Retry RetryPolicy
+	// RecordRetention 是已发布任务记录在 TaskStore 中的保留时长，期间重复投递的任务直接重新发布结果，
+	// 到期后由协调器定期删除（要求 TaskStore 实现 TaskPruner）。
+	RecordRetention time.Duration
+	// ArchiveResults 为 true 时把结果包写入 IPFS 并填充 TaskResult.ResultCID，要求 IPFS 客户端实现 IPFSAdder。
+	ArchiveResults bool
 }
 
 // applyDefaults 为缺失的配置填充默认值。
@@ -19,4 +42,23 @@ func (c *Config) applyDefaults() {
 	if c.JobTemplate == "" {
 		c.JobTemplate = "k8s/job.yaml"
 	}
+	if c.MaxConcurrentTasks <= 0 {
+		c.MaxConcurrentTasks = 4
+	}
+	if c.Store == nil {
+		c.Store = newMemoryTaskStore()
+	}
+	if c.ModuleDelivery == "" {
+		c.ModuleDelivery = DeliveryAuto
+	}
+	if c.ConfigMapLimit <= 0 {
+		c.ConfigMapLimit = 900 << 10
+	}
+	if c.FetchImage == "" {
+		c.FetchImage = "busybox:1.36"
+	}
+	if c.RecordRetention <= 0 {
+		c.RecordRetention = 24 * time.Hour
+	}
+	c.Retry.applyDefaults()
 }
@@ -4,163 +4,437 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 )
 
-// Coordinator 负责串联链上事件、IPFS 拉取以及 Kubernetes 调度。
+// Coordinator 负责串联链上事件、IPFS 拉取以及执行后端调度。
 type Coordinator struct {
 	cfg      Config
 	contract ContractClient
 	ipfs     IPFSClient
-	kube     *KubeManager
+	backend  ExecutionBackend
+	store    TaskStore
 	log      Logger
+	// pinner 是沿 Unwrap 链找到的可固定内容的 IPFS 客户端，为空时不固定模块。
+	pinner IPFSPinner
+	// adder 在启用结果归档时用于写入结果包。
+	adder IPFSAdder
+	// dirs 用于拉取 DataCID 指向的数据目录，为空时带数据目录的任务直接失败。
+	dirs IPFSDirectoryFetcher
+	// local 判断模块是否来自 IPFS 之外（如通过 API 上传），为空时所有模块都视为可经网关拉取。
+	local IPFSLocalModules
+	// inflight 跟踪处理中的任务，用于忽略重复投递并在任务来源撤回任务时中止。
+	inflight *inflight
 }
 
-// NewCoordinator 使用外部依赖构建协调器实例。
-func NewCoordinator(cfg Config, contract ContractClient, ipfs IPFSClient, kube *KubeManager) (*Coordinator, error) {
+// templateLoader 由需要 Job 模板的后端（KubeManager）实现。
+type templateLoader interface {
+	LoadTemplate(path string) error
+}
+
+// backendStarter 由需要在调度前启动后台组件（如 Informer）的后端实现。
+type backendStarter interface {
+	Start(ctx context.Context) error
+}
+
+// NewCoordinator 使用外部依赖构建协调器实例，backend 可以是 KubeManager 或任意 ExecutionBackend。
+func NewCoordinator(cfg Config, contract ContractClient, ipfs IPFSClient, backend ExecutionBackend) (*Coordinator, error) {
 	if contract == nil {
 		return nil, errors.New("contract client required")
 	}
 	if ipfs == nil {
 		return nil, errors.New("ipfs client required")
 	}
-	if kube == nil {
-		return nil, errors.New("kubernetes manager required")
+	if backend == nil {
+		return nil, errors.New("execution backend required")
 	}
 	cfg.applyDefaults()
 	log := defaultLogger(cfg.Log)
-	if err := kube.LoadTemplate(cfg.JobTemplate); err != nil {
+	if loader, ok := backend.(templateLoader); ok {
+		if err := loader.LoadTemplate(cfg.JobTemplate); err != nil {
+			return nil, err
+		}
+	}
+	pinner, _ := findIPFS[IPFSPinner](ipfs)
+	dirs, _ := findIPFS[IPFSDirectoryFetcher](ipfs)
+	local, _ := findIPFS[IPFSLocalModules](ipfs)
+	adder, err := resolveArchiver(cfg, ipfs)
+	if err != nil {
 		return nil, err
 	}
 	return &Coordinator{
 		cfg:      cfg,
 		contract: contract,
 		ipfs:     ipfs,
-		kube:     kube,
+		backend:  backend,
+		store:    cfg.Store,
 		log:      log,
+		pinner:   pinner,
+		adder:    adder,
+		dirs:     dirs,
+		local:    local,
+		inflight: newInflight(),
 	}, nil
 }
 
 // Run 持续运行直至上下文取消，驱动任务执行与清理。
+// 任务由 MaxConcurrentTasks 个 worker 并行处理；worker 全忙时订阅通道不再被读取，
+// 从而对任务来源形成背压。上下文取消后等待在途任务退出再返回。
 func (c *Coordinator) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if starter, ok := c.backend.(backendStarter); ok {
+		if err := starter.Start(runCtx); err != nil {
+			return fmt.Errorf("start execution backend: %w", err)
+		}
+	}
+
 	taskCh := make(chan TaskRequest)
 	errCh := make(chan error, 1)
 
 	go func() {
-		errCh <- c.contract.SubscribeTasks(ctx, taskCh)
+		defer close(taskCh)
+		if err := c.replayPending(runCtx, taskCh); err != nil {
+			errCh <- err
+			return
+		}
+		errCh <- c.contract.SubscribeTasks(runCtx, taskCh)
 	}()
+	go c.subscribeCancellations(runCtx)
+	go c.pruneRecords(runCtx)
 
+	var wg sync.WaitGroup
+	for i := 0; i < c.cfg.MaxConcurrentTasks; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			c.worker(runCtx, id, taskCh)
+		}(i)
+	}
+	c.log.Infof("coordinator started with %d workers", c.cfg.MaxConcurrentTasks)
+
+	select {
+	case <-ctx.Done():
+		wg.Wait()
+		return ctx.Err()
+	case err := <-errCh:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			c.log.Errorf("task subscription failed: %v", err)
+			cancel()
+			wg.Wait()
+			return err
+		}
+		// 订阅正常结束：通道随后关闭，worker 处理完剩余任务后退出。
+		wg.Wait()
+		return nil
+	}
+}
+
+// worker 从任务通道依次取出任务处理，通道关闭或上下文取消时退出。
+func (c *Coordinator) worker(ctx context.Context, id int, tasks <-chan TaskRequest) {
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case err := <-errCh:
-			if err != nil && !errors.Is(err, context.Canceled) {
-				c.log.Errorf("task subscription failed: %v", err)
-				return err
+			return
+		case task, ok := <-tasks:
+			if !ok {
+				return
 			}
-			return nil
-		case task := <-taskCh:
+			c.log.Infof("worker %d picked task %s", id, task.TaskID)
 			c.processTask(ctx, task)
 		}
 	}
 }
 
+// replayPending 在订阅前把 TaskStore 中尚未发布的任务重新投递给 worker，
+// processTask 会根据记录的阶段从断点继续。
+func (c *Coordinator) replayPending(ctx context.Context, out chan<- TaskRequest) error {
+	records, err := c.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list task store: %w", err)
+	}
+	for _, rec := range records {
+		if rec.Phase == PhasePublished {
+			continue
+		}
+		c.log.Infof("recovering task %s from phase %s", rec.Task.TaskID, rec.Phase)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- rec.Task:
+		}
+	}
+	return nil
+}
+
 // processTask 负责单个计算任务的完整生命周期，从拉取输入到发布结果。
+// 拉取、提交与等待阶段按 Config.Retry 重试暂时性错误，只有永久失败才会上报。
+// 若 TaskStore 中已有该任务的记录，则从记录的阶段继续，保证只执行一次：已发布的任务重新发布记录中的结果，
+// 同一 TaskID 正在处理时重复投递的任务被忽略。
+// 任务在截止时间（TaskRequest.Timeout/Deadline 或 Config.DefaultTaskTimeout）到期或被任务来源撤回后删除运行并上报失败。
 func (c *Coordinator) processTask(parent context.Context, task TaskRequest) {
-	ctx, cancel := context.WithCancel(parent)
+	if !c.inflight.claim(task.TaskID) {
+		c.log.Infof("task %s already in progress, ignoring duplicate", task.TaskID)
+		return
+	}
+	defer c.inflight.release(task.TaskID)
+
+	rec, found, err := c.store.Get(parent, task.TaskID)
+	if err != nil {
+		c.log.Warnf("load task %s from store: %v", task.TaskID, err)
+	}
+	if found {
+		switch rec.Phase {
+		case PhasePublished:
+			c.log.Infof("task %s already finished, re-publishing cached result", task.TaskID)
+			c.publish(parent, rec)
+			return
+		case PhaseFinished:
+			c.publish(parent, rec)
+			return
+		}
+	}
+
+	deadline := rec.Deadline
+	if !found || deadline.IsZero() {
+		deadline = c.taskDeadline(task, time.Now())
+	}
+	ctx, cancel := withTaskDeadline(parent, deadline)
 	defer cancel()
+	ctx, untrack := c.inflight.track(ctx, task.TaskID)
+	defer untrack()
+
+	if found && rec.Phase == PhaseJobCreated {
+		c.log.Infof("task %s: re-attaching to execution %s", task.TaskID, rec.Execution.Name)
+		c.awaitExecution(ctx, rec)
+		return
+	}
+	rec = TaskRecord{Task: task, StartedAt: time.Now(), Deadline: deadline}
+	if cancelled(ctx) {
+		c.log.Infof("task %s: cancelled before processing started", task.TaskID)
+		c.publishFailure(ctx, rec, errors.New("withdrawn before processing started"))
+		return
+	}
 	c.log.Infof("processing task %s (cid=%s)", task.TaskID, task.WasmCID)
+	c.savePhase(ctx, &rec, PhaseReceived)
 
 	if err := c.contract.AckTask(ctx, task.TaskID); err != nil {
 		c.log.Warnf("ack task %s: %v", task.TaskID, err)
 	}
+	c.savePhase(ctx, &rec, PhaseAcked)
 
-	module, err := c.ipfs.FetchModule(ctx, task.WasmCID)
+	var module []byte
+	err = c.retry(ctx, &rec, stageFetchModule, func(ctx context.Context) (err error) {
+		module, err = c.ipfs.FetchModule(ctx, task.WasmCID)
+		return err
+	})
 	if err != nil {
 		c.log.Errorf("fetch module for %s: %v", task.TaskID, err)
-		c.publishFailure(ctx, task, fmt.Errorf("fetch module: %w", err))
+		c.publishFailure(ctx, rec, fmt.Errorf("fetch module: %w", err))
 		return
 	}
+	task.ModuleLocal = c.local != nil && c.local.IsLocal(task.WasmCID)
+	if c.pinner != nil {
+		if err := c.pinner.Pin(ctx, task.WasmCID); err != nil {
+			c.log.Warnf("pin module %s for %s: %v", task.WasmCID, task.TaskID, err)
+		}
+	}
 
 	if len(task.InputJSON) == 0 && task.InputCID != "" {
-		inputBytes, err := c.ipfs.FetchModule(ctx, task.InputCID)
+		var inputBytes []byte
+		err := c.retry(ctx, &rec, stageFetchInput, func(ctx context.Context) (err error) {
+			inputBytes, err = c.ipfs.FetchModule(ctx, task.InputCID)
+			return err
+		})
 		if err != nil {
 			c.log.Errorf("fetch input for %s: %v", task.TaskID, err)
-			c.publishFailure(ctx, task, fmt.Errorf("fetch input: %w", err))
+			c.publishFailure(ctx, rec, fmt.Errorf("fetch input: %w", err))
 			return
 		}
 		task.InputJSON = inputBytes
 	}
 
-	jobName, configMaps, err := c.kube.CreateJob(ctx, c.cfg, task, module)
+	if task.DataCID != "" {
+		files, err := c.fetchData(ctx, &rec)
+		if err != nil {
+			c.log.Errorf("fetch data for %s: %v", task.TaskID, err)
+			c.publishFailure(ctx, rec, fmt.Errorf("fetch data: %w", err))
+			return
+		}
+		task.DataFiles = files
+	}
+	c.savePhase(ctx, &rec, PhaseFetched)
+
+	var exec Execution
+	err = c.retry(ctx, &rec, stageSubmit, func(ctx context.Context) (err error) {
+		exec, err = c.backend.Submit(ctx, c.cfg, task, module)
+		return err
+	})
 	if err != nil {
 		c.log.Errorf("create job for %s: %v", task.TaskID, err)
-		c.publishFailure(ctx, task, fmt.Errorf("create job: %w", err))
+		c.publishFailure(ctx, rec, fmt.Errorf("create job: %w", err))
 		return
 	}
+	rec.Execution = exec
+	c.savePhase(ctx, &rec, PhaseJobCreated)
 
-	defer c.kube.DeleteArtifacts(context.Background(), jobName, configMaps...)
+	c.awaitExecution(ctx, rec)
+}
 
-	job, err := c.kube.WaitForJob(ctx, jobName)
+// fetchData 拉取任务的数据目录并固定，供后端以只读方式挂载。
+func (c *Coordinator) fetchData(ctx context.Context, rec *TaskRecord) (map[string][]byte, error) {
+	if c.dirs == nil {
+		return nil, errors.New("data directories require an ipfs client that can fetch directories")
+	}
+	var files map[string][]byte
+	err := c.retry(ctx, rec, stageFetchData, func(ctx context.Context) (err error) {
+		files, err = c.dirs.FetchDirectory(ctx, rec.Task.DataCID)
+		return err
+	})
 	if err != nil {
-		c.log.Errorf("wait job %s: %v", jobName, err)
-		c.publishFailure(ctx, task, fmt.Errorf("wait job: %w", err))
+		return nil, err
+	}
+	if c.pinner != nil {
+		if err := c.pinner.Pin(ctx, rec.Task.DataCID); err != nil {
+			c.log.Warnf("pin data %s for %s: %v", rec.Task.DataCID, rec.Task.TaskID, err)
+		}
+	}
+	return files, nil
+}
+
+// awaitExecution 等待运行结束并汇总结果；协调器退出导致的中断会保留运行以便重启后接管。
+func (c *Coordinator) awaitExecution(ctx context.Context, rec TaskRecord) {
+	task := rec.Task
+	exec := rec.Execution
+	var status ExecutionStatus
+	err := c.retry(ctx, &rec, stageWait, func(ctx context.Context) (err error) {
+		status, err = c.backend.Wait(ctx, exec)
+		return err
+	})
+	if err != nil {
+		switch {
+		case deadlineExceeded(ctx):
+			c.log.Warnf("task %s: deadline exceeded, deleting execution %s", task.TaskID, exec.Name)
+		case cancelled(ctx):
+			c.log.Infof("task %s: cancelled, deleting execution %s", task.TaskID, exec.Name)
+		case ctx.Err() != nil:
+			c.log.Warnf("task %s: interrupted, execution %s left for recovery", task.TaskID, exec.Name)
+			return
+		default:
+			c.log.Errorf("wait job %s: %v", exec.Name, err)
+		}
+		c.backend.Cleanup(context.Background(), exec)
+		c.publishFailure(ctx, rec, fmt.Errorf("wait job: %w", err))
 		return
 	}
 
-	logs, err := c.kube.FetchJobLogs(ctx, jobName)
+	logs, err := c.backend.Logs(ctx, exec)
 	if err != nil {
-		c.log.Warnf("fetch logs %s: %v", jobName, err)
+		c.log.Warnf("fetch logs %s: %v", exec.Name, err)
 	}
+	c.backend.Cleanup(context.Background(), exec)
 
 	result := TaskResult{
 		TaskID:     task.TaskID,
-		Success:    job.Status.Succeeded > 0,
+		Success:    status.Succeeded,
 		Logs:       logs,
+		StartedAt:  rec.StartedAt,
 		FinishedAt: time.Now(),
-		Metadata:   task.ResultMetadata,
+		Metadata:   resultMetadata(rec),
 	}
 
-	if job.Status.Succeeded == 0 {
-		if len(job.Status.Conditions) > 0 {
-			result.Error = fmt.Errorf("job failed: %s", job.Status.Conditions[0].Message)
-		} else {
-			result.Error = fmt.Errorf("job failed without condition")
+	if !status.Succeeded {
+		result.FailureReason = FailureExecution
+		result.Error = errors.New(status.Message)
+		if reason, msg, ok := executionFailure(status.Result); ok {
+			result.FailureReason = reason
+			result.Error = fmt.Errorf("%s: %s", reason, msg)
 		}
 	} else {
-		result.OutputValue = extractOutputValue(logs)
+		if len(status.Result) == 0 {
+			// 执行器总会经 termination message 或结果 ConfigMap 回传结果，缺失即视为失败，不从日志中猜测。
+			result.Success = false
+			result.FailureReason = FailureExecution
+			result.Error = errors.New("executor reported no result")
+		} else if err := applyExecutionOutput(&result, status.Result); err != nil {
+			result.Success = false
+			result.Error = fmt.Errorf("decode result.json: %w", err)
+		}
 	}
 
-	if err := c.contract.PublishResult(ctx, result); err != nil {
-		c.log.Errorf("publish result %s: %v", task.TaskID, err)
-	}
+	c.finish(ctx, rec, result)
 }
 
 // publishFailure 在任务失败时向合约层上报错误结果。
-func (c *Coordinator) publishFailure(ctx context.Context, task TaskRequest, err error) {
+// 协调器退出导致的失败不上报，任务保留在 TaskStore 中等待重启恢复；截止时间到期则以 deadline-exceeded 上报，
+// 被任务来源撤回则以 cancelled 上报。
+func (c *Coordinator) publishFailure(ctx context.Context, rec TaskRecord, err error) {
+	reason := FailureError
+	if ctx.Err() != nil {
+		switch {
+		case deadlineExceeded(ctx):
+			reason = FailureDeadlineExceeded
+			err = fmt.Errorf("%w: %v", errTaskDeadline, err)
+		case cancelled(ctx):
+			reason = FailureCancelled
+			err = fmt.Errorf("%w: %v", errTaskCancelled, err)
+		default:
+			c.log.Warnf("task %s: interrupted before completion, left for recovery", rec.Task.TaskID)
+			return
+		}
+	} else if errors.Is(err, ErrIntegrity) {
+		reason = FailureIntegrity
+	}
 	res := TaskResult{
-		TaskID:     task.TaskID,
-		Success:    false,
-		Error:      err,
-		FinishedAt: time.Now(),
-		Metadata:   task.ResultMetadata,
+		TaskID:        rec.Task.TaskID,
+		Success:       false,
+		Error:         err,
+		StartedAt:     rec.StartedAt,
+		FinishedAt:    time.Now(),
+		Metadata:      resultMetadata(rec),
+		FailureReason: reason,
+	}
+	c.finish(ctx, rec, res)
+}
+
+// finish 先归档（如启用）并持久化结果再发布，确保发布失败或进程崩溃后仍可重试且不重复归档。
+func (c *Coordinator) finish(ctx context.Context, rec TaskRecord, result TaskResult) {
+	ctx, cancel := settleContext(ctx)
+	defer cancel()
+	c.archiveResult(ctx, &rec, &result)
+	rec.Result = &result
+	rec.ResultError = ""
+	if result.Error != nil {
+		rec.ResultError = result.Error.Error()
+	}
+	c.savePhase(ctx, &rec, PhaseFinished)
+	c.publish(ctx, rec)
+}
+
+// publish 发布记录中的结果，成功后把任务标记为 published。
+func (c *Coordinator) publish(ctx context.Context, rec TaskRecord) {
+	if rec.Result == nil {
+		c.log.Errorf("task %s: finished record without result", rec.Task.TaskID)
+		return
 	}
-	if pubErr := c.contract.PublishResult(ctx, res); pubErr != nil {
-		c.log.Errorf("publish failure %s: %v", task.TaskID, pubErr)
+	result := *rec.Result
+	if rec.ResultError != "" {
+		result.Error = errors.New(rec.ResultError)
 	}
+	if err := c.contract.PublishResult(ctx, result); err != nil {
+		c.log.Errorf("publish result %s: %v", rec.Task.TaskID, err)
+		return
+	}
+	c.savePhase(ctx, &rec, PhasePublished)
 }
 
-// extractOutputValue 从 Job 日志末尾筛选最后一条非空行，作为原始结果值。
-func extractOutputValue(logs string) string {
-	lines := strings.Split(logs, "\n")
-	for i := len(lines) - 1; i >= 0; i-- {
-		line := strings.TrimSpace(lines[i])
-		if line == "" {
-			continue
-		}
-		return line
+// savePhase 更新记录阶段并写入 TaskStore，写入失败只记录警告不阻断任务。
+func (c *Coordinator) savePhase(ctx context.Context, rec *TaskRecord, phase TaskPhase) {
+	rec.Phase = phase
+	rec.UpdatedAt = time.Now()
+	if err := c.store.Put(ctx, *rec); err != nil {
+		c.log.Warnf("task %s: persist phase %s: %v", rec.Task.TaskID, phase, err)
 	}
-	return ""
 }
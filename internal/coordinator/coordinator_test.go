@@ -0,0 +1,108 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingContract 投递固定任务后结束订阅，确认时读取 TaskStore 中该任务的记录。
+type recordingContract struct {
+	store TaskStore
+	tasks []TaskRequest
+
+	mu        sync.Mutex
+	atAck     map[string]TaskRecord
+	published map[string]TaskResult
+}
+
+func (r *recordingContract) SubscribeTasks(ctx context.Context, out chan<- TaskRequest) error {
+	for _, task := range r.tasks {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- task:
+		}
+	}
+	return nil
+}
+
+func (r *recordingContract) AckTask(ctx context.Context, taskID string) error {
+	rec, _, err := r.store.Get(ctx, taskID)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.atAck[taskID] = rec
+	return err
+}
+
+func (r *recordingContract) PublishResult(ctx context.Context, result TaskResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published[result.TaskID] = result
+	return nil
+}
+
+// missingModules 对所有 CID 返回永久错误。
+type missingModules struct{}
+
+func (missingModules) FetchModule(ctx context.Context, cid string) ([]byte, error) {
+	return nil, errors.New("module not found")
+}
+
+// unusedBackend 用于模块拉取即失败、不会提交运行的任务。
+type unusedBackend struct{}
+
+func (unusedBackend) Submit(ctx context.Context, cfg Config, task TaskRequest, module []byte) (Execution, error) {
+	return Execution{}, errors.New("unexpected submit")
+}
+
+func (unusedBackend) Wait(ctx context.Context, exec Execution) (ExecutionStatus, error) {
+	return ExecutionStatus{}, errors.New("unexpected wait")
+}
+
+func (unusedBackend) Logs(ctx context.Context, exec Execution) (string, error) { return "", nil }
+
+func (unusedBackend) Cleanup(ctx context.Context, exec Execution) {}
+
+// TestTaskPersistedBeforeAck 确认任务在 AckTask 之前已写入 TaskStore，且 received 记录会在重启后重新投递。
+func TestTaskPersistedBeforeAck(t *testing.T) {
+	store := newMemoryTaskStore()
+	// 上次运行在交出任务后、确认前崩溃留下的记录。
+	lost := TaskRequest{TaskID: "lost", WasmCID: "mod"}
+	if err := store.Put(context.Background(), TaskRecord{Task: lost, Phase: PhaseReceived, UpdatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	contract := &recordingContract{
+		store:     store,
+		tasks:     []TaskRequest{{TaskID: "new", WasmCID: "mod"}},
+		atAck:     map[string]TaskRecord{},
+		published: map[string]TaskResult{},
+	}
+	coord, err := NewCoordinator(Config{
+		Log:   testLogger{t},
+		Store: store,
+		Retry: RetryPolicy{MaxAttempts: 1},
+	}, contract, missingModules{}, unusedBackend{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := coord.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	contract.mu.Lock()
+	defer contract.mu.Unlock()
+	for _, id := range []string{"lost", "new"} {
+		if rec := contract.atAck[id]; rec.Phase != PhaseReceived {
+			t.Errorf("%s: phase at ack = %q, want %q", id, rec.Phase, PhaseReceived)
+		}
+		if res, ok := contract.published[id]; !ok || res.Success {
+			t.Errorf("%s: published=%v result=%+v", id, ok, res)
+		}
+	}
+}
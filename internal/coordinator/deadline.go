@@ -0,0 +1,49 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// settleTimeout 限制超时任务在脱离截止时间后落盘、清理与发布结果的耗时。
+const settleTimeout = 30 * time.Second
+
+// errTaskDeadline 是任务上下文超过截止时间时的取消原因，用于与协调器退出区分。
+var errTaskDeadline = errors.New("task deadline exceeded")
+
+// taskDeadline 根据任务自身的 Deadline/Timeout 与默认时限计算截止时间，零值表示不限制。
+func (c *Coordinator) taskDeadline(task TaskRequest, start time.Time) time.Time {
+	deadline := task.Deadline
+	timeout := time.Duration(task.Timeout)
+	if timeout <= 0 && deadline.IsZero() {
+		timeout = c.cfg.DefaultTaskTimeout
+	}
+	if timeout > 0 {
+		if d := start.Add(timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	return deadline
+}
+
+// withTaskDeadline 为任务派生带截止时间的上下文，到期时以 errTaskDeadline 为取消原因。
+func withTaskDeadline(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if deadline.IsZero() {
+		return context.WithCancel(parent)
+	}
+	return context.WithDeadlineCause(parent, deadline, errTaskDeadline)
+}
+
+// deadlineExceeded 判断上下文是否因任务截止时间而结束，而非协调器退出。
+func deadlineExceeded(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errTaskDeadline)
+}
+
+// settleContext 返回脱离任务截止时间与撤回的上下文，保证超时或被撤回的任务仍能清理并上报失败。
+func settleContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if !deadlineExceeded(ctx) && !cancelled(ctx) {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), settleTimeout)
+}
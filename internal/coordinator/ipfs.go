@@ -0,0 +1,22 @@
+package coordinator
+
+// ipfsUnwrapper 由包装其他 IPFSClient 的装饰器（如磁盘缓存）实现。
+type ipfsUnwrapper interface {
+	Unwrap() IPFSClient
+}
+
+// findIPFS 沿 Unwrap 链查找实现可选接口 T（IPFSPinner、IPFSAdder 等）的客户端。
+func findIPFS[T any](client IPFSClient) (T, bool) {
+	for client != nil {
+		if v, ok := client.(T); ok {
+			return v, true
+		}
+		u, ok := client.(ipfsUnwrapper)
+		if !ok {
+			break
+		}
+		client = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,63 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// KubeManager 通过以下方法实现 ExecutionBackend：每次运行对应一个 Job 及其 ConfigMap。
+var _ ExecutionBackend = (*KubeManager)(nil)
+
+// Submit 创建 Job，Execution.Resources 记录本次创建的 ConfigMap。
+// 已有带相同 executor.wasm/task-id 标签的 Job 时直接接管，不重复创建。
+func (m *KubeManager) Submit(ctx context.Context, cfg Config, task TaskRequest, module []byte) (Execution, error) {
+	if exec, ok, err := m.AdoptJob(ctx, task.TaskID); err != nil || ok {
+		return exec, err
+	}
+	jobName, configMaps, err := m.CreateJob(ctx, cfg, task, module)
+	if err != nil {
+		return Execution{}, err
+	}
+	return Execution{TaskID: task.TaskID, Name: jobName, Resources: configMaps}, nil
+}
+
+// Wait 等待 Job 结束，并读取 termination message 或结果 ConfigMap 中的 result.json。
+// Job 成功但读取结果失败时返回错误，由协调器按暂时性错误重试。
+func (m *KubeManager) Wait(ctx context.Context, exec Execution) (ExecutionStatus, error) {
+	job, err := m.WaitForJob(ctx, exec.Name)
+	if err != nil {
+		return ExecutionStatus{}, err
+	}
+	status := ExecutionStatus{Succeeded: jobCondition(job, batchv1.JobComplete) != nil}
+	if !status.Succeeded {
+		if cond := jobCondition(job, batchv1.JobFailed); cond != nil {
+			status.Message = fmt.Sprintf("job failed: %s: %s", cond.Reason, cond.Message)
+		} else {
+			status.Message = "job failed without condition"
+		}
+	}
+	raw, err := m.FetchJobResult(ctx, exec.Name)
+	if err == nil && len(raw) == 0 {
+		raw, err = m.FetchResultConfigMap(ctx, exec.TaskID)
+	}
+	if err != nil {
+		if status.Succeeded {
+			return ExecutionStatus{}, fmt.Errorf("fetch result %s: %w", exec.Name, err)
+		}
+		m.log.Warnf("fetch result %s: %v", exec.Name, err)
+	}
+	status.Result = raw
+	return status, nil
+}
+
+// Logs 返回 Job Pod 的日志。
+func (m *KubeManager) Logs(ctx context.Context, exec Execution) (string, error) {
+	return m.FetchJobLogs(ctx, exec.Name)
+}
+
+// Cleanup 删除 Job 与 ConfigMap。
+func (m *KubeManager) Cleanup(ctx context.Context, exec Execution) {
+	m.DeleteArtifacts(ctx, exec.Name, exec.Resources...)
+}
@@ -0,0 +1,313 @@
+package coordinator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 模块投递方式。ConfigMap 受 etcd 对象大小（约 1MiB）限制，更大的模块需要分片或由 Pod 自行拉取。
+const (
+	DeliveryAuto      = "auto"
+	DeliveryConfigMap = "configmap"
+	DeliveryChunked   = "chunked"
+	DeliveryFetch     = "fetch"
+)
+
+const (
+	fetchContainerName     = "fetch-module"
+	fetchDataContainerName = "fetch-data"
+	wasmPartFormat         = wasmFileName + ".part-%03d"
+)
+
+// moduleDelivery 记录一次任务实际采用的模块投递方案。
+type moduleDelivery struct {
+	mode       string
+	configMaps []string
+	parts      int
+	sha256     string
+	url        string
+}
+
+// chooseDelivery 根据配置与模块大小确定投递方式；auto 模式下小模块走单个 ConfigMap，
+// 大模块在配置了 PodGatewayURL 时由 init 容器拉取，否则拆分为多个 ConfigMap。
+// 不来自 IPFS 的模块（TaskRequest.ModuleLocal）网关无法提供，fetch 模式下同样按 auto 规则改用 ConfigMap 或分片。
+func chooseDelivery(cfg Config, task TaskRequest, size int) (string, error) {
+	mode := cfg.ModuleDelivery
+	if mode == DeliveryFetch && task.ModuleLocal {
+		mode = DeliveryAuto
+	}
+	if mode == DeliveryAuto {
+		switch {
+		case size <= cfg.ConfigMapLimit:
+			mode = DeliveryConfigMap
+		case cfg.PodGatewayURL != "" && task.WasmCID != "" && !task.ModuleLocal:
+			mode = DeliveryFetch
+		default:
+			mode = DeliveryChunked
+		}
+	}
+	switch mode {
+	case DeliveryConfigMap:
+		if size > cfg.ConfigMapLimit {
+			return "", fmt.Errorf("module is %d bytes, exceeds configmap limit %d", size, cfg.ConfigMapLimit)
+		}
+	case DeliveryChunked:
+	case DeliveryFetch:
+		if cfg.PodGatewayURL == "" || task.WasmCID == "" {
+			return "", fmt.Errorf("fetch delivery requires PodGatewayURL and a module CID")
+		}
+	default:
+		return "", fmt.Errorf("unknown module delivery %q", mode)
+	}
+	return mode, nil
+}
+
+// createModuleSource 按投递方式创建模块相关的 ConfigMap，失败时回滚已创建的部分。
+func (m *KubeManager) createModuleSource(ctx context.Context, cfg Config, task TaskRequest, wasm []byte) (moduleDelivery, error) {
+	mode, err := chooseDelivery(cfg, task, len(wasm))
+	if err != nil {
+		return moduleDelivery{}, err
+	}
+	d := moduleDelivery{mode: mode}
+	base := m.configMapName(task.TaskID)
+
+	switch mode {
+	case DeliveryConfigMap:
+		m.log.Infof("task %s: creating module configmap %s", task.TaskID, base)
+		if err := m.createBinaryConfigMap(ctx, task, base, map[string][]byte{wasmFileName: wasm}); err != nil {
+			return d, fmt.Errorf("create module configmap: %w", err)
+		}
+		d.configMaps = []string{base}
+	case DeliveryChunked:
+		for off := 0; off < len(wasm); off += cfg.ConfigMapLimit {
+			end := min(off+cfg.ConfigMapLimit, len(wasm))
+			name := fmt.Sprintf("%s-%d", base, d.parts)
+			key := fmt.Sprintf(wasmPartFormat, d.parts)
+			if err := m.createBinaryConfigMap(ctx, task, name, map[string][]byte{key: wasm[off:end]}); err != nil {
+				m.deleteConfigMaps(ctx, d.configMaps)
+				return moduleDelivery{}, fmt.Errorf("create module chunk %d: %w", d.parts, err)
+			}
+			d.configMaps = append(d.configMaps, name)
+			d.parts++
+		}
+		m.log.Infof("task %s: module (%d bytes) split into %d configmaps", task.TaskID, len(wasm), d.parts)
+	case DeliveryFetch:
+		sum := sha256.Sum256(wasm)
+		d.sha256 = hex.EncodeToString(sum[:])
+		d.url = fmt.Sprintf("%s/%s", strings.TrimRight(cfg.PodGatewayURL, "/"), strings.TrimLeft(task.WasmCID, "/"))
+		m.log.Infof("task %s: module (%d bytes) will be fetched by init container from %s", task.TaskID, len(wasm), d.url)
+	}
+	return d, nil
+}
+
+// createBinaryConfigMap 创建带任务标签的二进制 ConfigMap。
+func (m *KubeManager) createBinaryConfigMap(ctx context.Context, task TaskRequest, name string, data map[string][]byte) error {
+	labels, annotations := taskMeta(task.TaskID)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   m.namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		BinaryData: data,
+	}
+	if _, err := m.client.CoreV1().ConfigMaps(m.namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		m.log.Errorf("task %s: create configmap %s failed: %v", task.TaskID, name, err)
+		return err
+	}
+	return nil
+}
+
+// applyModuleDelivery 把模块卷与（必要时的）init 容器写入 Pod 规格，并返回执行器需要的额外环境变量。
+func applyModuleDelivery(spec *corev1.PodSpec, cfg Config, d moduleDelivery) []corev1.EnvVar {
+	switch d.mode {
+	case DeliveryChunked:
+		sources := make([]corev1.VolumeProjection, 0, len(d.configMaps))
+		for _, name := range d.configMaps {
+			sources = append(sources, corev1.VolumeProjection{
+				ConfigMap: &corev1.ConfigMapProjection{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				},
+			})
+		}
+		ensureVolume(&spec.Volumes, wasmVolumeName, corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{Sources: sources},
+		})
+		return []corev1.EnvVar{{Name: "WASM_PARTS", Value: fmt.Sprint(d.parts)}}
+	case DeliveryFetch:
+		ensureVolume(&spec.Volumes, wasmVolumeName, corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}})
+		target := fmt.Sprintf("%s/%s", wasmMountPath, wasmFileName)
+		script := fmt.Sprintf(`wget -q -O %[1]s "$MODULE_URL" && echo "$MODULE_SHA256  %[1]s" | sha256sum -c -`, target)
+		init := corev1.Container{
+			Name:    fetchContainerName,
+			Image:   cfg.FetchImage,
+			Command: []string{"sh", "-c", script},
+			Env: []corev1.EnvVar{
+				{Name: "MODULE_URL", Value: d.url},
+				{Name: "MODULE_SHA256", Value: d.sha256},
+			},
+		}
+		ensureVolumeMount(&init, wasmVolumeName, wasmMountPath, false)
+		spec.InitContainers = append(spec.InitContainers, init)
+		return nil
+	default:
+		name := ""
+		if len(d.configMaps) > 0 {
+			name = d.configMaps[0]
+		}
+		ensureConfigMapVolume(&spec.Volumes, wasmVolumeName, name)
+		return nil
+	}
+}
+
+// dataDelivery 记录数据目录的投递方案：文件装入一个或多个 ConfigMap（items 与 configMaps 一一对应），
+// 或由 init 容器逐个从网关下载（fetch）。
+type dataDelivery struct {
+	configMaps []string
+	items      [][]corev1.KeyToPath
+	fetch      []dataFetch
+}
+
+// dataFetch 描述 init 容器下载的一个数据文件。
+type dataFetch struct {
+	path   string
+	url    string
+	sha256 string
+}
+
+func (d dataDelivery) empty() bool {
+	return len(d.configMaps) == 0 && len(d.fetch) == 0
+}
+
+// dataBucket 是装入单个 ConfigMap 的一组数据文件。
+type dataBucket struct {
+	data  map[string][]byte
+	items []corev1.KeyToPath
+	size  int
+}
+
+// packDataFiles 按路径顺序把文件装入若干 ConfigMap，每个不超过 limit 字节。ConfigMap 键不能含 "/"，
+// 因此使用 f0、f1… 作为键，再通过卷的 items 还原为目录结构。单个文件超过 limit 时返回错误。
+func packDataFiles(files map[string][]byte, limit int) ([]dataBucket, error) {
+	var buckets []dataBucket
+	for i, p := range slices.Sorted(maps.Keys(files)) {
+		data := files[p]
+		if len(data) > limit {
+			return nil, fmt.Errorf("data file %s is %d bytes, exceeds configmap limit %d", p, len(data), limit)
+		}
+		if len(buckets) == 0 || buckets[len(buckets)-1].size+len(data) > limit {
+			buckets = append(buckets, dataBucket{data: map[string][]byte{}})
+		}
+		b := &buckets[len(buckets)-1]
+		key := fmt.Sprintf("f%d", i)
+		b.data[key] = data
+		b.items = append(b.items, corev1.KeyToPath{Key: key, Path: p})
+		b.size += len(data)
+	}
+	return buckets, nil
+}
+
+// createDataSource 按数据目录大小选择投递方式，与模块的 auto 模式一致：不超过 ConfigMapLimit 时写入单个 ConfigMap；
+// 更大时在配置了 PodGatewayURL 的情况下由 init 容器从网关下载，否则拆分到多个 ConfigMap。失败时回滚已创建的部分。
+func (m *KubeManager) createDataSource(ctx context.Context, cfg Config, task TaskRequest) (dataDelivery, error) {
+	var d dataDelivery
+	size := 0
+	for _, data := range task.DataFiles {
+		size += len(data)
+	}
+	if size > cfg.ConfigMapLimit && cfg.PodGatewayURL != "" {
+		base := fmt.Sprintf("%s/%s", strings.TrimRight(cfg.PodGatewayURL, "/"), strings.Trim(task.DataCID, "/"))
+		for _, p := range slices.Sorted(maps.Keys(task.DataFiles)) {
+			sum := sha256.Sum256(task.DataFiles[p])
+			d.fetch = append(d.fetch, dataFetch{path: p, url: base + "/" + escapePath(p), sha256: hex.EncodeToString(sum[:])})
+		}
+		m.log.Infof("task %s: data directory (%d files, %d bytes) will be fetched by init container from %s", task.TaskID, len(d.fetch), size, base)
+		return d, nil
+	}
+
+	buckets, err := packDataFiles(task.DataFiles, cfg.ConfigMapLimit)
+	if err != nil {
+		return d, fmt.Errorf("%w; set PodGatewayURL to fetch large data files", err)
+	}
+	base := m.dataConfigMapName(task.TaskID)
+	for i, b := range buckets {
+		name := base
+		if len(buckets) > 1 {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		if err := m.createBinaryConfigMap(ctx, task, name, b.data); err != nil {
+			m.deleteConfigMaps(ctx, d.configMaps)
+			return dataDelivery{}, fmt.Errorf("create data configmap: %w", err)
+		}
+		d.configMaps = append(d.configMaps, name)
+		d.items = append(d.items, b.items)
+	}
+	m.log.Infof("task %s: data directory (%d files, %d bytes) stored in %d configmaps", task.TaskID, len(task.DataFiles), size, len(d.configMaps))
+	return d, nil
+}
+
+// applyDataDelivery 把数据目录卷与（必要时的）init 容器写入 Pod 规格。
+func applyDataDelivery(spec *corev1.PodSpec, cfg Config, d dataDelivery) {
+	switch {
+	case len(d.fetch) > 0:
+		ensureVolume(&spec.Volumes, dataVolumeName, corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}})
+		var script strings.Builder
+		script.WriteString("set -e\n")
+		for _, f := range d.fetch {
+			target := dataMountPath + "/" + f.path
+			fmt.Fprintf(&script, "mkdir -p \"$(dirname %[1]s)\"\nwget -q -O %[1]s %[2]s\necho %[3]s | sha256sum -c -\n",
+				shellQuote(target), shellQuote(f.url), shellQuote(f.sha256+"  "+target))
+		}
+		init := corev1.Container{
+			Name:    fetchDataContainerName,
+			Image:   cfg.FetchImage,
+			Command: []string{"sh", "-c", script.String()},
+		}
+		ensureVolumeMount(&init, dataVolumeName, dataMountPath, false)
+		spec.InitContainers = append(spec.InitContainers, init)
+	case len(d.configMaps) == 1:
+		ensureVolume(&spec.Volumes, dataVolumeName, corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: d.configMaps[0]},
+				Items:                d.items[0],
+			},
+		})
+	case len(d.configMaps) > 1:
+		sources := make([]corev1.VolumeProjection, 0, len(d.configMaps))
+		for i, name := range d.configMaps {
+			sources = append(sources, corev1.VolumeProjection{
+				ConfigMap: &corev1.ConfigMapProjection{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+					Items:                d.items[i],
+				},
+			})
+		}
+		ensureVolume(&spec.Volumes, dataVolumeName, corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{Sources: sources},
+		})
+	}
+}
+
+// escapePath 逐段转义相对路径，用于拼接网关 URL。
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// shellQuote 以单引号包裹字符串，使其在 sh 中按字面解释。
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -1,9 +1,13 @@
 package coordinator
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"regexp"
 	"strings"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -17,6 +21,9 @@ const (
 	labelJobTemplate = "executor.wasm/template"
 	controllerName   = "wasm-coordinator"
 
+	// annotationTaskID 保存完整的 TaskID；标签值限 63 个字符，labelTaskID 只存 TaskID 的摘要。
+	annotationTaskID = "executor.wasm/task-id"
+
 	wasmFileName    = "module.wasm"
 	wasmMountPath   = "/mnt/wasm"
 	sharedMountPath = "/mnt/shared"
@@ -25,6 +32,11 @@ const (
 	inputMountPath  = "/mnt/input"
 	inputVolumeName = "input-dir"
 	wasmVolumeName  = "wasm-dir"
+	dataMountPath   = "/mnt/data"
+	dataVolumeName  = "data-dir"
+
+	// terminationMessagePath 由 kubelet 读取并写入 Pod 状态，执行器把 result.json 同步写到这里。
+	terminationMessagePath = "/dev/termination-log"
 )
 
 // nameSanitizer 将任务 ID 清洗成合法的 Kubernetes 名称。
@@ -44,6 +56,23 @@ func sanitizeName(base string) string {
 	return base
 }
 
+// taskLabel 返回 TaskID 的标签值：SHA-256 十六进制的前 40 个字符。
+// EVM 等来源的 TaskID 超过标签值的 63 字符上限，完整 TaskID 记录在 annotationTaskID 注解中。
+func taskLabel(taskID string) string {
+	sum := sha256.Sum256([]byte(taskID))
+	return hex.EncodeToString(sum[:])[:40]
+}
+
+// taskMeta 返回任务所建对象共用的标签与注解。
+func taskMeta(taskID string) (map[string]string, map[string]string) {
+	labels := map[string]string{
+		labelManagedBy: controllerName,
+		labelTaskID:    taskLabel(taskID),
+	}
+	annotations := map[string]string{annotationTaskID: taskID}
+	return labels, annotations
+}
+
 func (m *KubeManager) configMapName(taskID string) string {
 	return fmt.Sprintf("wasm-task-%s", sanitizeName(taskID))
 }
@@ -52,28 +81,35 @@ func (m *KubeManager) inputConfigMapName(taskID string) string {
 	return fmt.Sprintf("wasm-input-%s", sanitizeName(taskID))
 }
 
+func (m *KubeManager) dataConfigMapName(taskID string) string {
+	return fmt.Sprintf("wasm-data-%s", sanitizeName(taskID))
+}
+
+func (m *KubeManager) resultConfigMapName(taskID string) string {
+	return fmt.Sprintf("wasm-result-%s", sanitizeName(taskID))
+}
+
 func (m *KubeManager) jobName(taskID string) string {
 	return fmt.Sprintf("wasm-job-%s", sanitizeName(taskID))
 }
 
 // buildJobSpec 根据模板注入任务专属 env、标签与 ConfigMap 卷。
-func (m *KubeManager) buildJobSpec(cfg Config, task TaskRequest, jobName, wasmCMName, inputCMName string) *batchv1.Job {
+func (m *KubeManager) buildJobSpec(cfg Config, task TaskRequest, jobName string, module moduleDelivery, inputCMName string, data dataDelivery, resultCMName string) *batchv1.Job {
 	tmpl := m.template.DeepCopy()
 
 	tmpl.Namespace = cfg.Namespace
 	tmpl.Name = jobName
-	tmpl.Labels = mergeLabels(tmpl.Labels, map[string]string{
-		labelManagedBy:   controllerName,
-		labelTaskID:      task.TaskID,
-		labelConfigMap:   wasmCMName,
-		labelJobTemplate: "executor-v1",
-	})
+	taskLabels, taskAnnotations := taskMeta(task.TaskID)
+	jobLabels := mergeLabels(map[string]string{labelJobTemplate: "executor-v1"}, taskLabels)
+	if len(module.configMaps) > 0 {
+		jobLabels[labelConfigMap] = module.configMaps[0]
+	}
+	tmpl.Labels = mergeLabels(tmpl.Labels, jobLabels)
+	tmpl.Annotations = mergeLabels(tmpl.Annotations, taskAnnotations)
 
 	podMeta := &tmpl.Spec.Template.ObjectMeta
-	podMeta.Labels = mergeLabels(podMeta.Labels, map[string]string{
-		labelManagedBy: controllerName,
-		labelTaskID:    task.TaskID,
-	})
+	podMeta.Labels = mergeLabels(podMeta.Labels, taskLabels)
+	podMeta.Annotations = mergeLabels(podMeta.Annotations, taskAnnotations)
 
 	appendEnv := func(envs []corev1.EnvVar, name, value string) []corev1.EnvVar {
 		if value == "" {
@@ -97,9 +133,18 @@ func (m *KubeManager) buildJobSpec(cfg Config, task TaskRequest, jobName, wasmCM
 	env = appendEnv(env, "WASM_PATH", fmt.Sprintf("%s/%s", wasmMountPath, wasmFileName))
 	env = appendEnv(env, "OUTPUT_PATH", fmt.Sprintf("%s/%s", sharedMountPath, resultFileName))
 	env = appendEnv(env, "INPUT_PATH", inputPath)
+	env = appendEnv(env, "RESULT_MESSAGE_PATH", terminationMessagePath)
+	env = appendEnv(env, "RESULT_CONFIGMAP", resultCMName)
+	env = appendEnv(env, "RESULT_NAMESPACE", m.namespace)
 	if task.Entry != "" {
 		env = appendEnv(env, "ENTRY", task.Entry)
 	}
+	if !data.empty() {
+		env = appendEnv(env, "DATA_PATH", dataMountPath)
+	}
+	for _, extra := range applyModuleDelivery(&tmpl.Spec.Template.Spec, cfg, module) {
+		env = appendEnv(env, extra.Name, extra.Value)
+	}
 	for k, v := range tn(task.Args) {
 		env = appendEnv(env, k, v)
 	}
@@ -110,27 +155,49 @@ func (m *KubeManager) buildJobSpec(cfg Config, task TaskRequest, jobName, wasmCM
 			c.Image = cfg.ExecutorImage
 		}
 		c.Env = env
+		c.TerminationMessagePath = terminationMessagePath
+		c.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+		ensureVolumeMount(c, wasmVolumeName, wasmMountPath, true)
 		if inputCMName != "" {
 			ensureVolumeMount(c, inputVolumeName, inputMountPath, true)
 		}
+		if !data.empty() {
+			ensureVolumeMount(c, dataVolumeName, dataMountPath, true)
+		}
 	}
 
-	vols := &tmpl.Spec.Template.Spec.Volumes
-	ensureConfigMapVolume(vols, wasmVolumeName, wasmCMName)
 	if inputCMName != "" {
-		ensureConfigMapVolume(vols, inputVolumeName, inputCMName)
+		ensureConfigMapVolume(&tmpl.Spec.Template.Spec.Volumes, inputVolumeName, inputCMName)
 	}
+	applyDataDelivery(&tmpl.Spec.Template.Spec, cfg, data)
 
 	return tmpl
 }
 
+// applyActiveDeadline 把任务剩余时限写入 activeDeadlineSeconds，让 Kubernetes 同样强制截止；
+// 模板中已有更短的时限时保留模板值。
+func applyActiveDeadline(job *batchv1.Job, deadline time.Time) {
+	secs := int64(math.Ceil(time.Until(deadline).Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	if cur := job.Spec.ActiveDeadlineSeconds; cur != nil && *cur <= secs {
+		return
+	}
+	job.Spec.ActiveDeadlineSeconds = &secs
+}
+
 // ensureConfigMapVolume 确保 Pod 规格中存在指向 cmName 的 ConfigMap 卷。
 func ensureConfigMapVolume(vols *[]corev1.Volume, name, cmName string) {
-	src := corev1.VolumeSource{
+	ensureVolume(vols, name, corev1.VolumeSource{
 		ConfigMap: &corev1.ConfigMapVolumeSource{
 			LocalObjectReference: corev1.LocalObjectReference{Name: cmName},
 		},
-	}
+	})
+}
+
+// ensureVolume 确保 Pod 规格中存在名为 name 的卷，已存在时替换其来源。
+func ensureVolume(vols *[]corev1.Volume, name string, src corev1.VolumeSource) {
 	for i := range *vols {
 		if (*vols)[i].Name == name {
 			(*vols)[i].VolumeSource = src
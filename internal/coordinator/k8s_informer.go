@@ -0,0 +1,128 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// informerResync 是 Job/Pod 缓存的周期性重同步间隔，兜底遗漏的事件。
+const informerResync = 5 * time.Minute
+
+// Start 启动按 managing-controller 标签过滤的 Job/Pod 共享 Informer，并等待缓存同步。
+// 重复调用是安全的；Informer 随 ctx 取消而停止。
+func (m *KubeManager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.jobLister != nil {
+		return nil
+	}
+
+	selector := labels.SelectorFromSet(map[string]string{labelManagedBy: controllerName}).String()
+	factory := informers.NewSharedInformerFactoryWithOptions(m.client, informerResync,
+		informers.WithNamespace(m.namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = selector
+		}),
+	)
+
+	// Informer 必须在 factory.Start 之前实例化，否则不会被启动。
+	jobInformer := factory.Batch().V1().Jobs()
+	podInformer := factory.Core().V1().Pods()
+	podSynced := podInformer.Informer().HasSynced
+	if _, err := jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    m.onJobEvent,
+		UpdateFunc: func(_, obj any) { m.onJobEvent(obj) },
+		DeleteFunc: m.onJobEvent,
+	}); err != nil {
+		return fmt.Errorf("register job handler: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(),
+		jobInformer.Informer().HasSynced, podSynced) {
+		factory.Shutdown()
+		return fmt.Errorf("wait for informer cache sync: %w", ctx.Err())
+	}
+
+	m.jobLister = jobInformer.Lister()
+	m.podLister = podInformer.Lister()
+	m.log.Infof("job/pod informers synced (namespace=%s selector=%s)", m.namespace, selector)
+	return nil
+}
+
+// onJobEvent 唤醒等待该 Job 的所有调用方。
+func (m *KubeManager) onJobEvent(obj any) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ch := range m.waiters[job.Name] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// watchJob 注册一个 Job 通知通道，返回的函数用于注销。
+func (m *KubeManager) watchJob(jobName string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	m.mu.Lock()
+	m.waiters[jobName] = append(m.waiters[jobName], ch)
+	m.mu.Unlock()
+
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		list := m.waiters[jobName]
+		for i := range list {
+			if list[i] == ch {
+				list = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(list) == 0 {
+			delete(m.waiters, jobName)
+		} else {
+			m.waiters[jobName] = list
+		}
+	}
+}
+
+// listJobPods 从 Pod 缓存中按选择器列出 Job 的 Pod。
+func (m *KubeManager) listJobPods(selector labels.Selector) ([]*corev1.Pod, error) {
+	if m.podLister == nil {
+		return nil, fmt.Errorf("pod informer not started")
+	}
+	return m.podLister.Pods(m.namespace).List(selector)
+}
+
+// jobFinished 判断 Job 是否已处于终态（JobComplete 或 JobFailed 条件为 True）。
+// 单个 Pod 失败后 Job 仍可能按 backoffLimit 重试，不能据此判定结束；超过 activeDeadlineSeconds 时 JobFailed 的原因为 DeadlineExceeded。
+func jobFinished(job *batchv1.Job) bool {
+	return jobCondition(job, batchv1.JobComplete) != nil || jobCondition(job, batchv1.JobFailed) != nil
+}
+
+// jobCondition 返回 Job 中类型为 condType 且状态为 True 的条件，不存在时返回 nil。
+func jobCondition(job *batchv1.Job, condType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		if cond := &job.Status.Conditions[i]; cond.Type == condType && cond.Status == corev1.ConditionTrue {
+			return cond
+		}
+	}
+	return nil
+}
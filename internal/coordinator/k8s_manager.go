@@ -6,14 +6,16 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"time"
+	"sync"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/yaml"
@@ -21,10 +23,15 @@ import (
 
 // KubeManager 负责与 Kubernetes API 交互，贯穿任务创建、监控与清理。
 type KubeManager struct {
-	client    *kubernetes.Clientset
+	client    kubernetes.Interface
 	namespace string
 	log       Logger
 	template  *batchv1.Job
+
+	mu        sync.Mutex
+	jobLister batchlisters.JobLister
+	podLister corelisters.PodLister
+	waiters   map[string][]chan struct{}
 }
 
 // NewKubeManager 优先使用集群内配置，失败时回退到本地 kubeconfig。
@@ -42,11 +49,17 @@ func NewKubeManager(namespace string, log Logger) (*KubeManager, error) {
 		return nil, fmt.Errorf("build clientset: %w", err)
 	}
 
+	return NewKubeManagerForClient(cs, namespace, log), nil
+}
+
+// NewKubeManagerForClient 基于给定的 clientset 构造管理器，便于注入 fake clientset。
+func NewKubeManagerForClient(client kubernetes.Interface, namespace string, log Logger) *KubeManager {
 	return &KubeManager{
-		client:    cs,
+		client:    client,
 		namespace: namespace,
 		log:       defaultLogger(log),
-	}, nil
+		waiters:   map[string][]chan struct{}{},
+	}
 }
 
 // LoadTemplate 读取 Job 模板并缓存，后续任务可直接复用骨架。
@@ -64,7 +77,8 @@ func (m *KubeManager) LoadTemplate(path string) error {
 	return nil
 }
 
-// CreateJob 将 Wasm/输入写入 ConfigMap，并基于模板创建一次性 Job。
+// CreateJob 按投递方式准备 Wasm 模块、把输入写入 ConfigMap，并基于模板创建一次性 Job。
+// ctx 带截止时间时同步设置 Job 的 activeDeadlineSeconds。
 func (m *KubeManager) CreateJob(ctx context.Context, cfg Config, task TaskRequest, wasm []byte) (string, []string, error) {
 	if m.template == nil {
 		return "", nil, fmt.Errorf("job template not loaded")
@@ -73,39 +87,23 @@ func (m *KubeManager) CreateJob(ctx context.Context, cfg Config, task TaskReques
 	jobName := m.jobName(task.TaskID)
 	var configMaps []string
 
-	moduleCM := m.configMapName(task.TaskID)
-	m.log.Infof("task %s: creating module configmap %s", task.TaskID, moduleCM)
-	cm := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      moduleCM,
-			Namespace: m.namespace,
-			Labels: map[string]string{
-				labelManagedBy: controllerName,
-				labelTaskID:    task.TaskID,
-			},
-		},
-		BinaryData: map[string][]byte{
-			wasmFileName: wasm,
-		},
-	}
-	if _, err := m.client.CoreV1().ConfigMaps(m.namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
-		m.log.Errorf("task %s: create module configmap failed: %v", task.TaskID, err)
-		return "", nil, fmt.Errorf("create module configmap: %w", err)
+	module, err := m.createModuleSource(ctx, cfg, task, wasm)
+	if err != nil {
+		return "", nil, err
 	}
-	configMaps = append(configMaps, moduleCM)
+	configMaps = append(configMaps, module.configMaps...)
 
 	var inputCM string
 	if len(task.InputJSON) > 0 {
 		inputCM = m.inputConfigMapName(task.TaskID)
 		m.log.Infof("task %s: creating input configmap %s", task.TaskID, inputCM)
+		labels, annotations := taskMeta(task.TaskID)
 		in := &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      inputCM,
-				Namespace: m.namespace,
-				Labels: map[string]string{
-					labelManagedBy: controllerName,
-					labelTaskID:    task.TaskID,
-				},
+				Name:        inputCM,
+				Namespace:   m.namespace,
+				Labels:      labels,
+				Annotations: annotations,
 			},
 			Data: map[string]string{inputFileName: string(task.InputJSON)},
 		}
@@ -117,7 +115,28 @@ func (m *KubeManager) CreateJob(ctx context.Context, cfg Config, task TaskReques
 		configMaps = append(configMaps, inputCM)
 	}
 
-	job := m.buildJobSpec(cfg, task, jobName, moduleCM, inputCM)
+	var data dataDelivery
+	if len(task.DataFiles) > 0 {
+		data, err = m.createDataSource(ctx, cfg, task)
+		if err != nil {
+			m.deleteConfigMaps(ctx, configMaps)
+			return "", nil, err
+		}
+		configMaps = append(configMaps, data.configMaps...)
+	}
+
+	// 结果超过 termination message 上限时由执行器写入该 ConfigMap，预先创建使执行器只需 patch 权限。
+	resultCM := m.resultConfigMapName(task.TaskID)
+	if err := m.createResultConfigMap(ctx, task, resultCM); err != nil {
+		m.deleteConfigMaps(ctx, configMaps)
+		return "", nil, err
+	}
+	configMaps = append(configMaps, resultCM)
+
+	job := m.buildJobSpec(cfg, task, jobName, module, inputCM, data, resultCM)
+	if deadline, ok := ctx.Deadline(); ok {
+		applyActiveDeadline(job, deadline)
+	}
 	if _, err := m.client.BatchV1().Jobs(m.namespace).Create(ctx, job, metav1.CreateOptions{}); err != nil {
 		m.log.Errorf("task %s: create job %s failed: %v", task.TaskID, jobName, err)
 		m.deleteConfigMaps(ctx, configMaps)
@@ -128,55 +147,105 @@ func (m *KubeManager) CreateJob(ctx context.Context, cfg Config, task TaskReques
 	return jobName, configMaps, nil
 }
 
-// WaitForJob 轮询 Job 直到成功、失败或上下文被取消。
-func (m *KubeManager) WaitForJob(ctx context.Context, jobName string) (*batchv1.Job, error) {
-	m.log.Infof("waiting for job %s to complete", jobName)
-	err := wait.PollUntilContextCancel(ctx, 3*time.Second, true, func(ctx context.Context) (bool, error) {
-		job, err := m.client.BatchV1().Jobs(m.namespace).Get(ctx, jobName, metav1.GetOptions{})
-		if err != nil {
-			return false, err
+// AdoptJob 查找带有任务标签的已有 Job（例如上次创建后尚未记录阶段就崩溃，或任务被重复投递），
+// 找到时返回指向它及其 ConfigMap 的 Execution。没有 Job 但残留了该任务的 ConfigMap 时先删除，
+// 避免 CreateJob 因 AlreadyExists 失败；Job 正在删除时返回暂时性错误，由调用方稍后重试。
+// 标签只是 TaskID 的摘要，候选对象还须由 annotationTaskID 注解确认属于该任务。
+func (m *KubeManager) AdoptJob(ctx context.Context, taskID string) (Execution, bool, error) {
+	taskLabels, _ := taskMeta(taskID)
+	selector := labels.SelectorFromSet(taskLabels).String()
+	jobs, err := m.client.BatchV1().Jobs(m.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return Execution{}, false, fmt.Errorf("list jobs for task %s: %w", taskID, err)
+	}
+	cms, err := m.client.CoreV1().ConfigMaps(m.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return Execution{}, false, fmt.Errorf("list configmaps for task %s: %w", taskID, err)
+	}
+	var configMaps []string
+	for _, cm := range cms.Items {
+		if cm.Annotations[annotationTaskID] == taskID {
+			configMaps = append(configMaps, cm.Name)
 		}
-		if job.Status.Failed > 0 || job.Status.Succeeded > 0 {
-			return true, nil
+	}
+
+	var job *batchv1.Job
+	for i := range jobs.Items {
+		if jobs.Items[i].Annotations[annotationTaskID] != taskID {
+			continue
+		}
+		if job == nil || jobs.Items[i].CreationTimestamp.After(job.CreationTimestamp.Time) {
+			job = &jobs.Items[i]
 		}
-		return false, nil
-	})
-	if err != nil {
-		m.log.Warnf("wait job %s interrupted: %v", jobName, err)
-		return nil, err
 	}
-	job, err := m.client.BatchV1().Jobs(m.namespace).Get(ctx, jobName, metav1.GetOptions{})
-	if err == nil {
-		m.log.Infof("job %s finished (succeeded=%d failed=%d)", jobName, job.Status.Succeeded, job.Status.Failed)
+	switch {
+	case job == nil:
+		if len(configMaps) > 0 {
+			m.log.Warnf("task %s: removing %d orphaned configmaps", taskID, len(configMaps))
+			m.deleteConfigMaps(ctx, configMaps)
+		}
+		return Execution{}, false, nil
+	case job.DeletionTimestamp != nil:
+		return Execution{}, false, Transient(fmt.Errorf("job %s for task %s is being deleted", job.Name, taskID))
 	}
-	return job, err
+	m.log.Infof("task %s: adopting existing job %s", taskID, job.Name)
+	return Execution{TaskID: taskID, Name: job.Name, Resources: configMaps}, true, nil
 }
 
-// FetchJobLogs 拉取 Job 第一个 Pod 的日志，供协调器解析输出。
-func (m *KubeManager) FetchJobLogs(ctx context.Context, jobName string) (string, error) {
-	job, err := m.client.BatchV1().Jobs(m.namespace).Get(ctx, jobName, metav1.GetOptions{})
-	if err != nil {
-		return "", err
+// createResultConfigMap 创建空的结果 ConfigMap，执行器只在结果超过 termination message 上限时写入。
+func (m *KubeManager) createResultConfigMap(ctx context.Context, task TaskRequest, name string) error {
+	if err := m.createBinaryConfigMap(ctx, task, name, nil); err != nil {
+		return fmt.Errorf("create result configmap: %w", err)
 	}
+	return nil
+}
 
-	var selector labels.Selector
-	if job.Spec.Selector != nil {
-		selector = labels.Set(job.Spec.Selector.MatchLabels).AsSelector()
-	} else {
-		selector = labels.SelectorFromSet(map[string]string{
-			labelManagedBy: controllerName,
-			labelTaskID:    job.Labels[labelTaskID],
-		})
+// WaitForJob 阻塞等待 Job 成功、失败或上下文被取消。
+// 状态来自 Start 启动的共享 Informer，Job 变更时通过通知通道唤醒，不再轮询 API Server。
+func (m *KubeManager) WaitForJob(ctx context.Context, jobName string) (*batchv1.Job, error) {
+	if m.jobLister == nil {
+		return nil, fmt.Errorf("job informer not started")
 	}
-	pods, err := m.client.CoreV1().Pods(m.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	m.log.Infof("waiting for job %s to complete", jobName)
+
+	notify, stop := m.watchJob(jobName)
+	defer stop()
+
+	for {
+		job, err := m.jobLister.Jobs(m.namespace).Get(jobName)
+		switch {
+		case err == nil:
+			if jobFinished(job) {
+				m.log.Infof("job %s finished (succeeded=%d failed=%d)", jobName, job.Status.Succeeded, job.Status.Failed)
+				return job.DeepCopy(), nil
+			}
+		case apierrors.IsNotFound(err):
+			// 缓存可能尚未收到刚创建的 Job，向 API Server 确认其确实存在。
+			if _, err := m.client.BatchV1().Jobs(m.namespace).Get(ctx, jobName, metav1.GetOptions{}); err != nil {
+				m.log.Warnf("wait job %s interrupted: %v", jobName, err)
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			m.log.Warnf("wait job %s interrupted: %v", jobName, ctx.Err())
+			return nil, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+// FetchJobLogs 拉取 Job 第一个 Pod 的日志，供协调器解析输出。
+func (m *KubeManager) FetchJobLogs(ctx context.Context, jobName string) (string, error) {
+	pod, err := m.jobPod(ctx, jobName)
 	if err != nil {
 		return "", err
 	}
-	if len(pods.Items) == 0 {
-		return "", fmt.Errorf("no pod found for job %s", jobName)
-	}
 
-	req := m.client.CoreV1().Pods(m.namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{})
+	req := m.client.CoreV1().Pods(m.namespace).GetLogs(pod.Name, &corev1.PodLogOptions{})
 	stream, err := req.Stream(ctx)
 	if err != nil {
 		return "", err
@@ -195,6 +264,70 @@ func (m *KubeManager) FetchJobLogs(ctx context.Context, jobName string) (string,
 	return builder.String(), nil
 }
 
+// FetchJobResult 读取执行器经 termination message 回传的 result.json 原文。
+// 消息受 kubelet 4KiB 限制，超限的结果由执行器写入结果 ConfigMap，见 FetchResultConfigMap。
+func (m *KubeManager) FetchJobResult(ctx context.Context, jobName string) ([]byte, error) {
+	pod, err := m.jobPod(ctx, jobName)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if term := status.State.Terminated; term != nil && strings.TrimSpace(term.Message) != "" {
+			return []byte(term.Message), nil
+		}
+	}
+	return nil, nil
+}
+
+// FetchResultConfigMap 读取执行器写入结果 ConfigMap 的 result.json，未写入时返回空字节。
+func (m *KubeManager) FetchResultConfigMap(ctx context.Context, taskID string) ([]byte, error) {
+	cm, err := m.client.CoreV1().ConfigMaps(m.namespace).Get(ctx, m.resultConfigMapName(taskID), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(cm.Data[resultFileName]), nil
+}
+
+// jobPod 返回 Job 的代表 Pod：优先选择已成功的 Pod，否则取最近创建的一个。
+func (m *KubeManager) jobPod(ctx context.Context, jobName string) (*corev1.Pod, error) {
+	job, err := m.client.BatchV1().Jobs(m.namespace).Get(ctx, jobName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var selector labels.Selector
+	if job.Spec.Selector != nil {
+		selector = labels.Set(job.Spec.Selector.MatchLabels).AsSelector()
+	} else {
+		selector = labels.SelectorFromSet(map[string]string{
+			labelManagedBy: controllerName,
+			labelTaskID:    job.Labels[labelTaskID],
+		})
+	}
+	pods, err := m.listJobPods(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no pod found for job %s", jobName)
+	}
+
+	chosen := pods[0]
+	for _, pod := range pods[1:] {
+		switch {
+		case pod.Status.Phase == corev1.PodSucceeded && chosen.Status.Phase != corev1.PodSucceeded:
+			chosen = pod
+		case (pod.Status.Phase == corev1.PodSucceeded) == (chosen.Status.Phase == corev1.PodSucceeded) &&
+			pod.CreationTimestamp.After(chosen.CreationTimestamp.Time):
+			chosen = pod
+		}
+	}
+	return chosen, nil
+}
+
 // DeleteArtifacts 删除 Job 以及本轮创建的 ConfigMap，避免资源残留。
 func (m *KubeManager) DeleteArtifacts(ctx context.Context, jobName string, configMaps ...string) {
 	m.log.Infof("cleaning up job %s", jobName)
@@ -0,0 +1,427 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testLogger 把协调器日志写入测试输出。
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Infof(format string, args ...any)  { l.t.Logf("[INFO] "+format, args...) }
+func (l testLogger) Warnf(format string, args ...any)  { l.t.Logf("[WARN] "+format, args...) }
+func (l testLogger) Errorf(format string, args ...any) { l.t.Logf("[ERROR] "+format, args...) }
+
+const testNamespace = "tasks"
+
+// evmTaskID 是 EVM 来源形式的 TaskID，长度超过标签值的 63 字符上限。
+const evmTaskID = "0x5f2c1d0e9b8a7f6e5d4c3b2a1908f7e6d5c4b3a29180f7e6d5c4b3a2918070f6"
+
+var testModule = []byte("\x00asm\x01\x00\x00\x00")
+
+// newTestKubeManager 返回基于 fake clientset、已启动 Informer 的 KubeManager。
+func newTestKubeManager(t *testing.T) (*KubeManager, *fake.Clientset, Config) {
+	t.Helper()
+	client := fake.NewClientset()
+	m := NewKubeManagerForClient(client, testNamespace, testLogger{t})
+	if err := m.LoadTemplate("../../k8s/job.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Namespace: testNamespace}
+	cfg.applyDefaults()
+	return m, client, cfg
+}
+
+// eventually 轮询 cond 直到成立，超时则失败。
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// finishJob 为 Job 创建一个已终止的 Pod（termination message 为 message），并把 Job 标记为成功。
+// 先等待 Informer 缓存看到 Job 与 Pod，确保 WaitForJob 能收到随后的状态变更。
+func finishJob(t *testing.T, m *KubeManager, client *fake.Clientset, jobName, message string) {
+	t.Helper()
+	job := createJobPod(t, m, client, jobName, jobName+"-pod", corev1.PodSucceeded, message)
+	job.Status.Succeeded = 1
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	updateJobStatus(t, client, job)
+}
+
+// createJobPod 为 Job 创建一个处于 phase 的已终止 Pod，等待 Informer 缓存看到它后返回 Job 的当前对象。
+func createJobPod(t *testing.T, m *KubeManager, client *fake.Clientset, jobName, podName string, phase corev1.PodPhase, message string) *batchv1.Job {
+	t.Helper()
+	ctx := context.Background()
+	job, err := client.BatchV1().Jobs(testNamespace).Get(ctx, jobName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "job in informer cache", func() bool {
+		_, err := m.jobLister.Jobs(testNamespace).Get(jobName)
+		return err == nil
+	})
+
+	var exitCode int32
+	if phase == corev1.PodFailed {
+		exitCode = 1
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              podName,
+			Namespace:         testNamespace,
+			Labels:            job.Spec.Template.Labels,
+			CreationTimestamp: metav1.Now(),
+		},
+		Status: corev1.PodStatus{
+			Phase: phase,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "executor",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: exitCode,
+					Message:  message,
+				}},
+			}},
+		},
+	}
+	if _, err := client.CoreV1().Pods(testNamespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "pod in informer cache", func() bool {
+		_, err := m.podLister.Pods(testNamespace).Get(pod.Name)
+		return err == nil
+	})
+	return job
+}
+
+func updateJobStatus(t *testing.T, client *fake.Clientset, job *batchv1.Job) {
+	t.Helper()
+	if _, err := client.BatchV1().Jobs(testNamespace).UpdateStatus(context.Background(), job, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKubeManagerSubmitLabelsAndAdopts(t *testing.T) {
+	m, client, cfg := newTestKubeManager(t)
+	ctx := context.Background()
+	task := TaskRequest{TaskID: evmTaskID, WasmCID: "bafymodule", InputJSON: []byte(`{"n":10}`)}
+
+	exec, err := m.Submit(ctx, cfg, task, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := client.BatchV1().Jobs(testNamespace).Get(ctx, exec.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, meta := range []metav1.ObjectMeta{job.ObjectMeta, job.Spec.Template.ObjectMeta} {
+		for k, v := range meta.Labels {
+			if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+				t.Errorf("label %s=%q invalid: %v", k, v, errs)
+			}
+		}
+		if got := meta.Annotations[annotationTaskID]; got != evmTaskID {
+			t.Errorf("annotation %s = %q, want %q", annotationTaskID, got, evmTaskID)
+		}
+	}
+	env := map[string]string{}
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	if env["RESULT_CONFIGMAP"] != m.resultConfigMapName(evmTaskID) || env["RESULT_NAMESPACE"] != testNamespace {
+		t.Errorf("result configmap env = %q/%q", env["RESULT_NAMESPACE"], env["RESULT_CONFIGMAP"])
+	}
+	if !slices.Contains(exec.Resources, m.resultConfigMapName(evmTaskID)) || !slices.Contains(exec.Resources, m.inputConfigMapName(evmTaskID)) {
+		t.Errorf("resources = %v", exec.Resources)
+	}
+
+	// 重复投递接管已有 Job，不再创建新对象。
+	again, err := m.Submit(ctx, cfg, task, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Name != exec.Name {
+		t.Errorf("adopted job %s, want %s", again.Name, exec.Name)
+	}
+	slices.Sort(exec.Resources)
+	slices.Sort(again.Resources)
+	if !slices.Equal(again.Resources, exec.Resources) {
+		t.Errorf("adopted resources %v, want %v", again.Resources, exec.Resources)
+	}
+	jobs, err := client.BatchV1().Jobs(testNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 1 {
+		t.Errorf("got %d jobs, want 1", len(jobs.Items))
+	}
+
+	m.Cleanup(ctx, exec)
+	if _, err := client.BatchV1().Jobs(testNamespace).Get(ctx, exec.Name, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("job after cleanup: %v", err)
+	}
+	cms, err := client.CoreV1().ConfigMaps(testNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cms.Items) != 0 {
+		t.Errorf("%d configmaps left after cleanup", len(cms.Items))
+	}
+}
+
+func TestKubeManagerWaitReadsTerminationMessage(t *testing.T) {
+	m, client, cfg := newTestKubeManager(t)
+	ctx := context.Background()
+	exec, err := m.Submit(ctx, cfg, TaskRequest{TaskID: "small"}, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type waitResult struct {
+		status ExecutionStatus
+		err    error
+	}
+	done := make(chan waitResult, 1)
+	go func() {
+		status, err := m.Wait(ctx, exec)
+		done <- waitResult{status, err}
+	}()
+
+	message := `{"entry":"run","args":[],"results":[],"status":"ok"}`
+	finishJob(t, m, client, exec.Name, message)
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if !res.status.Succeeded || string(res.status.Result) != message {
+			t.Fatalf("status = %+v (result %s)", res.status, res.status.Result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Wait did not return after job succeeded")
+	}
+}
+
+func TestKubeManagerWaitReadsResultConfigMap(t *testing.T) {
+	m, client, cfg := newTestKubeManager(t)
+	ctx := context.Background()
+	exec, err := m.Submit(ctx, cfg, TaskRequest{TaskID: "large"}, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过 termination message 上限的结果由执行器写入结果 ConfigMap，termination message 为空。
+	large := `{"entry":"run","output":"` + strings.Repeat("x", 8192) + `","status":"ok"}`
+	cms := client.CoreV1().ConfigMaps(testNamespace)
+	cm, err := cms.Get(ctx, m.resultConfigMapName("large"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.Data = map[string]string{resultFileName: large}
+	if _, err := cms.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	finishJob(t, m, client, exec.Name, "")
+
+	status, err := m.Wait(ctx, exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Succeeded || string(status.Result) != large {
+		t.Fatalf("succeeded=%v result=%.40s", status.Succeeded, status.Result)
+	}
+}
+
+func TestKubeManagerWaitForJobCancelled(t *testing.T) {
+	m, _, cfg := newTestKubeManager(t)
+	exec, err := m.Submit(context.Background(), cfg, TaskRequest{TaskID: "stuck"}, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := m.WaitForJob(ctx, exec.Name); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitForJob error = %v, want deadline exceeded", err)
+	}
+}
+
+// TestKubeManagerWaitRetriedJob 确认首个 Pod 失败后 Job 仍在按 backoffLimit 重试时不被视为结束，
+// 重试成功后读取成功 Pod 的结果。
+func TestKubeManagerWaitRetriedJob(t *testing.T) {
+	m, client, cfg := newTestKubeManager(t)
+	ctx := context.Background()
+	exec, err := m.Submit(ctx, cfg, TaskRequest{TaskID: "retried"}, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan ExecutionStatus, 1)
+	go func() {
+		status, err := m.Wait(ctx, exec)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- status
+	}()
+
+	job := createJobPod(t, m, client, exec.Name, exec.Name+"-first", corev1.PodFailed, `{"status":"error","error":"node lost"}`)
+	job.Status.Failed = 1
+	updateJobStatus(t, client, job)
+	eventually(t, "failed count in informer cache", func() bool {
+		job, err := m.jobLister.Jobs(testNamespace).Get(exec.Name)
+		return err == nil && job.Status.Failed == 1
+	})
+	select {
+	case status := <-done:
+		t.Fatalf("Wait returned after first pod failure: %+v", status)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	message := `{"entry":"run","args":[],"results":[],"status":"ok"}`
+	finishJob(t, m, client, exec.Name, message)
+	select {
+	case status := <-done:
+		if !status.Succeeded || string(status.Result) != message {
+			t.Fatalf("status = %+v (result %s)", status, status.Result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Wait did not return after retry succeeded")
+	}
+}
+
+func TestKubeManagerWaitDeadlineExceeded(t *testing.T) {
+	m, client, cfg := newTestKubeManager(t)
+	ctx := context.Background()
+	exec, err := m.Submit(ctx, cfg, TaskRequest{TaskID: "slow"}, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := createJobPod(t, m, client, exec.Name, exec.Name+"-pod", corev1.PodFailed, "")
+	job.Status.Failed = 1
+	job.Status.Conditions = []batchv1.JobCondition{{
+		Type:    batchv1.JobFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  batchv1.JobReasonDeadlineExceeded,
+		Message: "Job was active longer than specified deadline",
+	}}
+	updateJobStatus(t, client, job)
+
+	status, err := m.Wait(ctx, exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Succeeded || !strings.Contains(status.Message, "DeadlineExceeded") {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+// dataVolume 返回 Job 中的数据目录卷。
+func dataVolume(t *testing.T, job *batchv1.Job) corev1.Volume {
+	t.Helper()
+	for _, v := range job.Spec.Template.Spec.Volumes {
+		if v.Name == dataVolumeName {
+			return v
+		}
+	}
+	t.Fatalf("job %s has no data volume", job.Name)
+	return corev1.Volume{}
+}
+
+func TestKubeManagerDataDirectoryDelivery(t *testing.T) {
+	m, client, cfg := newTestKubeManager(t)
+	ctx := context.Background()
+	cfg.ConfigMapLimit = 16
+	files := map[string][]byte{
+		"a.txt":     []byte("0123456789"),
+		"b/c.txt":   []byte("0123456789"),
+		"b/d e.bin": []byte("0123"),
+	}
+
+	// 超过单个 ConfigMap 上限的目录拆分到多个 ConfigMap，以 projected 卷合并挂载。
+	exec, err := m.Submit(ctx, cfg, TaskRequest{TaskID: "chunked", DataCID: "bafydir", DataFiles: files}, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := client.BatchV1().Jobs(testNamespace).Get(ctx, exec.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	projected := dataVolume(t, job).Projected
+	if projected == nil || len(projected.Sources) != 2 {
+		t.Fatalf("data volume = %+v, want projected volume with 2 sources", dataVolume(t, job))
+	}
+	paths := map[string]string{}
+	for _, src := range projected.Sources {
+		cm, err := client.CoreV1().ConfigMaps(testNamespace).Get(ctx, src.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range src.ConfigMap.Items {
+			paths[item.Path] = string(cm.BinaryData[item.Key])
+		}
+	}
+	for p, want := range files {
+		if paths[p] != string(want) {
+			t.Errorf("data file %s = %q, want %q", p, paths[p], want)
+		}
+	}
+
+	// 单个文件超过上限且没有网关时无法投递。
+	big := map[string][]byte{"big": []byte(strings.Repeat("x", 17))}
+	if _, err := m.Submit(ctx, cfg, TaskRequest{TaskID: "too-big", DataCID: "bafybig", DataFiles: big}, testModule); err == nil {
+		t.Fatal("expected error for data file larger than configmap limit")
+	}
+
+	// 配置了网关时由 init 容器逐个下载并校验。
+	cfg.PodGatewayURL = "http://gateway.ipfs:8080/ipfs/"
+	exec, err = m.Submit(ctx, cfg, TaskRequest{TaskID: "fetched", DataCID: "bafydir/sub", DataFiles: files}, testModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err = client.BatchV1().Jobs(testNamespace).Get(ctx, exec.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataVolume(t, job).EmptyDir == nil {
+		t.Errorf("fetched data volume is not an emptyDir")
+	}
+	var script string
+	for _, c := range job.Spec.Template.Spec.InitContainers {
+		if c.Name == fetchDataContainerName {
+			script = c.Command[len(c.Command)-1]
+		}
+	}
+	for _, want := range []string{
+		"'http://gateway.ipfs:8080/ipfs/bafydir/sub/b/d%20e.bin'",
+		"'/mnt/data/b/d e.bin'",
+		"sha256sum -c -",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("fetch script missing %q:\n%s", want, script)
+		}
+	}
+	if len(exec.Resources) != 2 {
+		t.Errorf("fetched data created configmaps: %v", exec.Resources)
+	}
+}
@@ -0,0 +1,100 @@
+package coordinator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// executionOutput 对应执行器写入 result.json 的结构。
+type executionOutput struct {
+	Entry        string          `json:"entry"`
+	Args         []TypedValue    `json:"args"`
+	Results      []TypedValue    `json:"results"`
+	Output       json.RawMessage `json:"output"`
+	OutputBase64 string          `json:"output_base64"`
+	Status       string          `json:"status"`
+	Error        string          `json:"error"`
+}
+
+// executionFailure 从失败运行的 result.json 中提取失败分类与错误信息，ok 表示结果可用。
+func executionFailure(raw []byte) (reason FailureReason, message string, ok bool) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "", "", false
+	}
+	var out executionOutput
+	if err := json.Unmarshal(raw, &out); err != nil || out.Status == "" {
+		return "", "", false
+	}
+	switch out.Status {
+	case string(FailureTimeout):
+		reason = FailureTimeout
+	case string(FailureOutOfFuel):
+		reason = FailureOutOfFuel
+	default:
+		reason = FailureExecution
+	}
+	return reason, out.Error, true
+}
+
+// TypedValue 是执行器报告的带 Wasm 类型（i32/i64/f32/f64）的参数或返回值。
+// Value 为 JSON 数字原文（非有限浮点数为字符串），Raw 为 64 位原始编码。
+type TypedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+	Raw   uint64          `json:"raw"`
+}
+
+// UnmarshalJSON 兼容旧版执行器输出的裸 uint64 数字。
+func (v *TypedValue) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != '{' {
+		raw, err := strconv.ParseUint(string(trimmed), 10, 64)
+		if err != nil {
+			return fmt.Errorf("decode legacy value %s: %w", trimmed, err)
+		}
+		*v = TypedValue{Value: json.RawMessage(trimmed), Raw: raw}
+		return nil
+	}
+	type plain TypedValue
+	return json.Unmarshal(data, (*plain)(v))
+}
+
+// String 以 type:value 形式输出，便于日志阅读。
+func (v TypedValue) String() string {
+	if v.Type == "" {
+		return string(v.Value)
+	}
+	return v.Type + ":" + string(v.Value)
+}
+
+// applyExecutionOutput 解析 result.json 原文并填充 TaskResult 的结构化字段。
+func applyExecutionOutput(result *TaskResult, raw []byte) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return errors.New("empty result")
+	}
+	var out executionOutput
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return err
+	}
+	result.OutputValue = string(raw)
+	result.Entry = out.Entry
+	result.Args = out.Args
+	result.Results = out.Results
+	switch {
+	case len(out.Output) > 0:
+		result.OutputBytes = []byte(out.Output)
+	case out.OutputBase64 != "":
+		data, err := base64.StdEncoding.DecodeString(out.OutputBase64)
+		if err != nil {
+			return fmt.Errorf("decode output_base64: %w", err)
+		}
+		result.OutputBytes = data
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// 重试阶段名，同时作为 TaskResult.Metadata 中 attempts.<stage> 的键后缀。
+const (
+	stageFetchModule = "fetch-module"
+	stageFetchInput  = "fetch-input"
+	stageFetchData   = "fetch-data"
+	stageSubmit      = "submit"
+	stageWait        = "wait"
+)
+
+// RetryPolicy 控制 processTask 各阶段遇到暂时性错误时的重试行为。
+type RetryPolicy struct {
+	// MaxAttempts 是单个阶段的最大尝试次数（含首次），1 表示不重试。
+	MaxAttempts int
+	// InitialBackoff 是首次重试前的等待时间，之后按 Multiplier 指数增长，不超过 MaxBackoff。
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter 是等待时间的随机浮动比例（0~1），0 表示不抖动。
+	Jitter float64
+	// Retryable 判断错误是否值得重试，为空时使用 IsRetryable。
+	Retryable func(error) bool
+}
+
+// applyDefaults 为缺失的字段填充默认值。
+func (p *RetryPolicy) applyDefaults() {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 500 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsRetryable
+	}
+}
+
+// backoff 返回第 attempt 次失败后的等待时间。
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	d = math.Min(d, float64(p.MaxBackoff))
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// Transient 把 err 标记为可重试的暂时性错误，供适配器声明网关 5xx、限流等可恢复失败。
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return transientError{err: err}
+}
+
+type transientError struct{ err error }
+
+func (e transientError) Error() string   { return e.err.Error() }
+func (e transientError) Unwrap() error   { return e.err }
+func (e transientError) Transient() bool { return true }
+
+// IsRetryable 是默认的错误分类：显式标记的暂时性错误、API Server 冲突/超时/限流/5xx
+// 以及网络超时、连接被拒或重置视为可重试，其余一律视为永久失败。
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var marked interface{ Transient() bool }
+	if errors.As(err, &marked) {
+		return marked.Transient()
+	}
+	if apierrors.IsConflict(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) || apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsUnexpectedServerError(err) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retry 按 RetryPolicy 执行一个阶段，并把尝试次数记入 rec.Attempts。
+// 上下文结束、错误不可重试或次数耗尽时返回最后一次的错误。
+func (c *Coordinator) retry(ctx context.Context, rec *TaskRecord, stage string, fn func(context.Context) error) error {
+	policy := c.cfg.Retry
+	for attempt := 1; ; attempt++ {
+		if rec.Attempts == nil {
+			rec.Attempts = map[string]int{}
+		}
+		rec.Attempts[stage]++
+		err := fn(ctx)
+		if err == nil || ctx.Err() != nil || attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return err
+		}
+		delay := policy.backoff(attempt)
+		c.log.Warnf("task %s: %s attempt %d/%d failed, retrying in %s: %v",
+			rec.Task.TaskID, stage, attempt, policy.MaxAttempts, delay.Round(time.Millisecond), err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// resultMetadata 复制任务的 ResultMetadata 并附加各阶段尝试次数，避免修改任务请求本身。
+func resultMetadata(rec TaskRecord) map[string]string {
+	if len(rec.Task.ResultMetadata) == 0 && len(rec.Attempts) == 0 {
+		return nil
+	}
+	meta := make(map[string]string, len(rec.Task.ResultMetadata)+len(rec.Attempts))
+	for k, v := range rec.Task.ResultMetadata {
+		meta[k] = v
+	}
+	for stage, n := range rec.Attempts {
+		meta["attempts."+stage] = strconv.Itoa(n)
+	}
+	return meta
+}
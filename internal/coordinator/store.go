@@ -0,0 +1,88 @@
+package coordinator
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxPruneInterval 是清理已发布记录的最长间隔，保留时长更短时按保留时长清理。
+const maxPruneInterval = time.Hour
+
+// memoryTaskStore 是未配置持久化存储时使用的进程内实现，重启后状态丢失。
+type memoryTaskStore struct {
+	mu      sync.Mutex
+	records map[string]TaskRecord
+}
+
+func newMemoryTaskStore() *memoryTaskStore {
+	return &memoryTaskStore{records: map[string]TaskRecord{}}
+}
+
+// Get 返回任务记录，第二个返回值表示记录是否存在。
+func (s *memoryTaskStore) Get(ctx context.Context, taskID string) (TaskRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[taskID]
+	return rec, ok, nil
+}
+
+// Put 以任务 ID 为键覆盖写入记录。
+func (s *memoryTaskStore) Put(ctx context.Context, rec TaskRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.Task.TaskID] = rec
+	return nil
+}
+
+// List 按更新时间返回全部记录。
+func (s *memoryTaskStore) List(ctx context.Context) ([]TaskRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]TaskRecord, 0, len(s.records))
+	for _, rec := range s.records {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.Before(out[j].UpdatedAt) })
+	return out, nil
+}
+
+// Prune 删除 UpdatedAt 早于 before 的已发布记录。
+func (s *memoryTaskStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, rec := range s.records {
+		if rec.Phase == PhasePublished && rec.UpdatedAt.Before(before) {
+			delete(s.records, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// pruneRecords 定期删除超过 Config.RecordRetention 的已发布记录，使 TaskStore 与 replayPending 的开销不随历史任务增长。
+// TaskStore 未实现 TaskPruner 时直接返回。
+func (c *Coordinator) pruneRecords(ctx context.Context) {
+	pruner, ok := c.store.(TaskPruner)
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(min(c.cfg.RecordRetention, maxPruneInterval))
+	defer ticker.Stop()
+	for {
+		n, err := pruner.Prune(ctx, time.Now().Add(-c.cfg.RecordRetention))
+		switch {
+		case err != nil && ctx.Err() == nil:
+			c.log.Warnf("prune task store: %v", err)
+		case n > 0:
+			c.log.Infof("pruned %d published task records older than %s", n, c.cfg.RecordRetention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
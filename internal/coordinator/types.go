@@ -2,29 +2,153 @@ package coordinator
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"time"
 )
 
 // TaskRequest 表示一次计算任务。
 type TaskRequest struct {
-	TaskID         string
-	WasmCID        string
-	InputCID       string
-	Entry          string
-	Args           map[string]string
-	InputJSON      []byte
-	ResultMetadata map[string]string
+	TaskID         string            `json:"task_id"`
+	WasmCID        string            `json:"wasm_cid"`
+	InputCID       string            `json:"input_cid,omitempty"`
+	Entry          string            `json:"entry,omitempty"`
+	Args           map[string]string `json:"args,omitempty"`
+	InputJSON      []byte            `json:"input_json,omitempty"`
+	ResultMetadata map[string]string `json:"result_metadata,omitempty"`
+	// DataCID 指向 UnixFS 目录（可带路径，如 bafy.../data），整个目录以只读方式挂载给模块。
+	// WasmCID 与 InputCID 同样可以写成 "<目录 CID>/路径"。DataFiles 由协调器拉取后填充，不参与序列化。
+	DataCID   string            `json:"data_cid,omitempty"`
+	DataFiles map[string][]byte `json:"-"`
+	// ModuleLocal 由协调器在模块不来自 IPFS（见 IPFSLocalModules）时设置，不参与序列化。
+	ModuleLocal bool `json:"-"`
+	// Timeout 限制任务从开始处理到结果产出的总时长；Deadline 是绝对截止时间。
+	// 两者都设置时取较早者，都为空时使用 Config.DefaultTaskTimeout。
+	Timeout  Duration  `json:"timeout,omitempty"`
+	Deadline time.Time `json:"deadline,omitzero"`
+}
+
+// Duration 是 JSON 友好的时长：编码为 "1m30s" 形式的字符串，
+// 解码时接受 Go 时长字符串（"30s"、"1m30s"）或表示秒数的数字（30、1.5）。
+type Duration time.Duration
+
+// MarshalJSON 以 Go 时长字符串编码。
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON 解码时长字符串或秒数。
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case float64:
+		if math.IsNaN(v) || math.Abs(v) > math.MaxInt64/float64(time.Second) {
+			return fmt.Errorf("duration %v seconds out of range", v)
+		}
+		*d = Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", v, err)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s: want string or number of seconds", data)
+	}
+	return nil
 }
 
 // TaskResult 描述任务执行结果。
 type TaskResult struct {
-	TaskID      string
-	Success     bool
-	OutputValue string
-	Logs        string
-	FinishedAt  time.Time
-	Error       error
-	Metadata    map[string]string
+	TaskID      string            `json:"task_id"`
+	Success     bool              `json:"success"`
+	OutputValue string            `json:"output_value,omitempty"`
+	Entry       string            `json:"entry,omitempty"`
+	Args        []TypedValue      `json:"args,omitempty"`
+	Results     []TypedValue      `json:"results,omitempty"`
+	OutputBytes []byte            `json:"output_bytes,omitempty"` // buffer ABI 模式下 guest 返回的字节
+	Logs        string            `json:"logs,omitempty"`
+	StartedAt   time.Time         `json:"started_at,omitzero"`
+	FinishedAt  time.Time         `json:"finished_at"`
+	Error       error             `json:"-"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+	// ResultCID 是归档到 IPFS 的结果包 CID，未启用 Config.ArchiveResults 时为空。
+	ResultCID string `json:"result_cid,omitempty"`
+}
+
+// FailureReason 对失败结果分类，便于上游区分处理。
+type FailureReason string
+
+const (
+	// FailureError 表示协调器侧的通用失败（拉取、调度等）。
+	FailureError FailureReason = "error"
+	// FailureExecution 表示模块运行失败（trap、入口缺失等）。
+	FailureExecution FailureReason = "execution-error"
+	// FailureTimeout 表示执行器因 TIMEOUT_SEC 终止了模块。
+	FailureTimeout FailureReason = "timeout"
+	// FailureOutOfFuel 表示模块的函数调用次数超过 FUEL_LIMIT，执行器终止了模块。
+	FailureOutOfFuel FailureReason = "out-of-fuel"
+	// FailureDeadlineExceeded 表示任务超过了协调器侧的截止时间（TaskRequest.Timeout/Deadline）。
+	FailureDeadlineExceeded FailureReason = "deadline-exceeded"
+	// FailureIntegrity 表示拉取到的模块或输入与其 CID 不符。
+	FailureIntegrity FailureReason = "integrity"
+	// FailureCancelled 表示任务来源撤回了任务（见 TaskCanceller）。
+	FailureCancelled FailureReason = "cancelled"
+)
+
+// ErrIntegrity 表示内容哈希与 CID 不一致，IPFS 适配器以它包装校验失败，协调器据此归类为 FailureIntegrity。
+var ErrIntegrity = errors.New("integrity check failed")
+
+// TaskPhase 标识任务在协调器内的生命周期阶段。
+type TaskPhase string
+
+const (
+	// PhaseReceived 在确认任务之前写入：任务来源交出任务后即视为已投递，崩溃后只能由 TaskStore 恢复。
+	PhaseReceived   TaskPhase = "received"
+	PhaseAcked      TaskPhase = "acked"
+	PhaseFetched    TaskPhase = "fetched"
+	PhaseJobCreated TaskPhase = "job-created"
+	PhaseFinished   TaskPhase = "finished"
+	PhasePublished  TaskPhase = "published"
+)
+
+// TaskRecord 是 TaskStore 持久化的任务状态，足以在重启后恢复任务。
+type TaskRecord struct {
+	Task        TaskRequest `json:"task"`
+	Phase       TaskPhase   `json:"phase"`
+	Execution   Execution   `json:"execution"`
+	Result      *TaskResult `json:"result,omitempty"`
+	ResultError string      `json:"result_error,omitempty"`
+	// Attempts 记录各阶段的尝试次数，随结果写入 TaskResult.Metadata。
+	Attempts map[string]int `json:"attempts,omitempty"`
+	// StartedAt/Deadline 在首次处理时确定并持久化，重启恢复不会重新计时。
+	StartedAt time.Time `json:"started_at,omitzero"`
+	Deadline  time.Time `json:"deadline,omitzero"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Execution 标识执行后端中一次已提交的运行，需可序列化以便重启后接管。
+type Execution struct {
+	TaskID    string   `json:"task_id"`
+	Name      string   `json:"name"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// ExecutionStatus 描述一次运行结束后的状态。
+type ExecutionStatus struct {
+	Succeeded bool
+	Message   string
+	// Result 是执行器写出的 result.json 原文，后端无法获取时为空。
+	Result []byte
 }
 
 // ContractClient 抽象链上交互。
@@ -34,11 +158,59 @@ type ContractClient interface {
 	PublishResult(ctx context.Context, result TaskResult) error
 }
 
+// TaskCanceller 由能撤回任务的 ContractClient 实现（可选）。协调器在订阅任务的同时调用
+// SubscribeCancellations，收到 TaskID 后中止该任务、删除其运行，并以 FailureCancelled 上报结果。
+type TaskCanceller interface {
+	SubscribeCancellations(ctx context.Context, out chan<- string) error
+}
+
 // IPFSClient 抽象 Wasm 模块下载。
 type IPFSClient interface {
 	FetchModule(ctx context.Context, cid string) ([]byte, error)
 }
 
+// IPFSPinner 由能固定内容的 IPFS 客户端实现，协调器拉取模块后固定，避免节点回收正在使用的模块。
+type IPFSPinner interface {
+	Pin(ctx context.Context, cid string) error
+}
+
+// IPFSAdder 由能写入内容的 IPFS 客户端实现，返回写入内容的 CID。
+type IPFSAdder interface {
+	Add(ctx context.Context, name string, data []byte) (string, error)
+}
+
+// IPFSLocalModules 由持有非 IPFS 来源模块（如通过 API 上传）的 IPFSClient 包装实现。
+// 这类模块无法经网关按 CID 下载，Kubernetes 后端对其只使用 ConfigMap 或分片投递。
+type IPFSLocalModules interface {
+	IsLocal(ref string) bool
+}
+
+// IPFSDirectoryFetcher 由能下载 UnixFS 目录的 IPFS 客户端实现，返回相对路径到文件内容的映射。
+type IPFSDirectoryFetcher interface {
+	FetchDirectory(ctx context.Context, ref string) (map[string][]byte, error)
+}
+
+// ExecutionBackend 抽象 Wasm 模块的实际运行环境（Kubernetes Job、进程内 wazero 等）。
+type ExecutionBackend interface {
+	Submit(ctx context.Context, cfg Config, task TaskRequest, module []byte) (Execution, error)
+	Wait(ctx context.Context, exec Execution) (ExecutionStatus, error)
+	Logs(ctx context.Context, exec Execution) (string, error)
+	Cleanup(ctx context.Context, exec Execution)
+}
+
+// TaskStore 抽象任务状态的持久化存储。
+type TaskStore interface {
+	Get(ctx context.Context, taskID string) (TaskRecord, bool, error)
+	Put(ctx context.Context, rec TaskRecord) error
+	List(ctx context.Context) ([]TaskRecord, error)
+}
+
+// TaskPruner 由能删除记录的 TaskStore 实现，协调器据此按 Config.RecordRetention 清理已发布的记录。
+type TaskPruner interface {
+	// Prune 删除 UpdatedAt 早于 before 的 published 记录，返回删除的数量。
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
 // Logger 提供基础日志输出。
 type Logger interface {
 	Infof(format string, args ...any)
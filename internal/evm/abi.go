@@ -0,0 +1,140 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const abiWord = 32
+
+// Selector 返回函数签名（如 "ackTask(bytes32)"）的 4 字节选择器。
+func Selector(signature string) []byte {
+	return Keccak256([]byte(signature))[:4]
+}
+
+// EventTopic 返回事件签名的 topic0。
+func EventTopic(signature string) [32]byte {
+	var t [32]byte
+	copy(t[:], Keccak256([]byte(signature)))
+	return t
+}
+
+// EncodeCall 编码一次合约调用：选择器 + ABI 编码的参数。
+func EncodeCall(signature string, args ...any) ([]byte, error) {
+	enc, err := EncodeArgs(args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(Selector(signature), enc...), nil
+}
+
+// EncodeArgs 按 ABI 规则编码参数，支持 [32]byte（bytes32）、bool、uint64、*big.Int（uint256）、
+// Address、string 与 []byte（bytes）。
+func EncodeArgs(args ...any) ([]byte, error) {
+	head := make([]byte, 0, len(args)*abiWord)
+	var tail []byte
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case [32]byte:
+			head = append(head, v[:]...)
+		case bool:
+			var n uint64
+			if v {
+				n = 1
+			}
+			head = append(head, abiUint(new(big.Int).SetUint64(n))...)
+		case uint64:
+			head = append(head, abiUint(new(big.Int).SetUint64(v))...)
+		case *big.Int:
+			if v.Sign() < 0 || v.BitLen() > 256 {
+				return nil, fmt.Errorf("abi: uint256 out of range: %s", v)
+			}
+			head = append(head, abiUint(v)...)
+		case Address:
+			head = append(head, make([]byte, abiWord-len(v))...)
+			head = append(head, v[:]...)
+		case string, []byte:
+			var b []byte
+			if s, ok := v.(string); ok {
+				b = []byte(s)
+			} else {
+				b = v.([]byte)
+			}
+			offset := len(args)*abiWord + len(tail)
+			head = append(head, abiUint(big.NewInt(int64(offset)))...)
+			tail = append(tail, abiUint(big.NewInt(int64(len(b))))...)
+			tail = append(tail, b...)
+			if pad := len(b) % abiWord; pad != 0 {
+				tail = append(tail, make([]byte, abiWord-pad)...)
+			}
+		default:
+			return nil, fmt.Errorf("abi: unsupported type %T", arg)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+func abiUint(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, abiWord))
+}
+
+// DecodeArgs 按类型列表解码 ABI 数据，支持 bytes32、bool、uint64、uint256、address、string 与 bytes，
+// 对应的 Go 类型分别为 [32]byte、bool、uint64、*big.Int、Address、string 与 []byte。
+func DecodeArgs(types []string, data []byte) ([]any, error) {
+	if len(data) < len(types)*abiWord {
+		return nil, errors.New("abi: data shorter than head")
+	}
+	out := make([]any, 0, len(types))
+	for i, typ := range types {
+		word := data[i*abiWord : (i+1)*abiWord]
+		switch typ {
+		case "bytes32":
+			var b [32]byte
+			copy(b[:], word)
+			out = append(out, b)
+		case "bool":
+			out = append(out, new(big.Int).SetBytes(word).Sign() != 0)
+		case "uint64":
+			n := new(big.Int).SetBytes(word)
+			if !n.IsUint64() {
+				return nil, fmt.Errorf("abi: argument %d overflows uint64", i)
+			}
+			out = append(out, n.Uint64())
+		case "uint256":
+			out = append(out, new(big.Int).SetBytes(word))
+		case "address":
+			var a Address
+			copy(a[:], word[abiWord-len(a):])
+			out = append(out, a)
+		case "string", "bytes":
+			b, err := abiDynamic(data, word)
+			if err != nil {
+				return nil, fmt.Errorf("abi: argument %d: %w", i, err)
+			}
+			if typ == "string" {
+				out = append(out, string(b))
+			} else {
+				out = append(out, b)
+			}
+		default:
+			return nil, fmt.Errorf("abi: unsupported type %q", typ)
+		}
+	}
+	return out, nil
+}
+
+// abiDynamic 读取 offset 指向的 length + 数据段，并检查边界。
+func abiDynamic(data, offsetWord []byte) ([]byte, error) {
+	off := new(big.Int).SetBytes(offsetWord)
+	if !off.IsInt64() || off.Int64() > int64(len(data)-abiWord) {
+		return nil, errors.New("offset out of range")
+	}
+	start := int(off.Int64())
+	size := new(big.Int).SetBytes(data[start : start+abiWord])
+	if !size.IsInt64() || size.Int64() > int64(len(data)-start-abiWord) {
+		return nil, errors.New("length out of range")
+	}
+	begin := start + abiWord
+	return data[begin : begin+int(size.Int64())], nil
+}
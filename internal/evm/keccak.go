@@ -0,0 +1,15 @@
+// Package evm 实现与 EVM 链交互所需的最小原语：Keccak-256、secp256k1 签名、RLP、ABI 编解码与
+// EIP-155 交易签名，避免为一个合约适配器引入完整的以太坊客户端库。密码学部分委托给
+// golang.org/x/crypto/sha3 与 decred 的 secp256k1 实现。
+package evm
+
+import "golang.org/x/crypto/sha3"
+
+// Keccak256 计算以太坊使用的 Keccak-256（原始 Keccak 填充，而非 SHA3-256）。
+func Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
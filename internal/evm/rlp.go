@@ -0,0 +1,63 @@
+package evm
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// EncodeRLP 按以太坊 RLP 规则编码 []byte、string、uint64、*big.Int、Address 及由它们组成的 []any 列表。
+// 整数编码为去掉前导零的大端字节，0 编码为空串。
+func EncodeRLP(v any) ([]byte, error) {
+	switch val := v.(type) {
+	case []byte:
+		return rlpString(val), nil
+	case string:
+		return rlpString([]byte(val)), nil
+	case uint64:
+		return rlpString(new(big.Int).SetUint64(val).Bytes()), nil
+	case *big.Int:
+		if val == nil {
+			return rlpString(nil), nil
+		}
+		if val.Sign() < 0 {
+			return nil, fmt.Errorf("rlp: negative integer %s", val)
+		}
+		return rlpString(val.Bytes()), nil
+	case Address:
+		return rlpString(val[:]), nil
+	case *Address:
+		// nil 表示合约创建交易的空 to 字段。
+		if val == nil {
+			return rlpString(nil), nil
+		}
+		return rlpString(val[:]), nil
+	case []any:
+		var payload []byte
+		for _, item := range val {
+			enc, err := EncodeRLP(item)
+			if err != nil {
+				return nil, err
+			}
+			payload = append(payload, enc...)
+		}
+		return append(rlpHeader(0xc0, len(payload)), payload...), nil
+	default:
+		return nil, fmt.Errorf("rlp: unsupported type %T", v)
+	}
+}
+
+func rlpString(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+// rlpHeader 生成字符串（offset 0x80）或列表（offset 0xc0）的长度前缀。
+func rlpHeader(offset byte, size int) []byte {
+	if size <= 55 {
+		return []byte{offset + byte(size)}
+	}
+	n := big.NewInt(int64(size)).Bytes()
+	return append([]byte{offset + 55 + byte(len(n))}, n...)
+}
@@ -0,0 +1,90 @@
+package evm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// compactSigMagic 是 decred 紧凑签名首字节的偏移：27 + 恢复标识（未压缩公钥）。
+const compactSigMagic = 27
+
+// Address 是 20 字节的以太坊账户地址。
+type Address [20]byte
+
+// ParseAddress 解析带或不带 0x 前缀的十六进制地址（不校验 EIP-55 大小写）。
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil || len(raw) != len(a) {
+		return a, fmt.Errorf("invalid address %q", s)
+	}
+	copy(a[:], raw)
+	return a, nil
+}
+
+// Hex 返回小写十六进制形式（带 0x 前缀）。
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// PrivateKey 是 secp256k1 私钥，签名使用常数时间的 decred 实现。
+type PrivateKey struct {
+	key *secp256k1.PrivateKey
+}
+
+// ParsePrivateKey 解析 32 字节十六进制私钥（可带 0x 前缀）。
+func ParsePrivateKey(s string) (*PrivateKey, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("private key must be 32 hex-encoded bytes")
+	}
+	var d secp256k1.ModNScalar
+	if overflow := d.SetByteSlice(raw); overflow || d.IsZero() {
+		return nil, errors.New("private key out of range")
+	}
+	return &PrivateKey{key: secp256k1.NewPrivateKey(&d)}, nil
+}
+
+// Address 返回私钥对应的账户地址：keccak256(X||Y) 的后 20 字节。
+func (k *PrivateKey) Address() Address {
+	return pubkeyAddress(k.key.PubKey())
+}
+
+// Sign 对 32 字节摘要做确定性（RFC 6979）签名，返回 r||s||v（v 为 0/1 的恢复标识），s 规范化为低值。
+func (k *PrivateKey) Sign(hash []byte) ([65]byte, error) {
+	var sig [65]byte
+	if len(hash) != 32 {
+		return sig, errors.New("hash must be 32 bytes")
+	}
+	compact := ecdsa.SignCompact(k.key, hash, false)
+	copy(sig[:64], compact[1:])
+	sig[64] = compact[0] - compactSigMagic
+	return sig, nil
+}
+
+// RecoverAddress 由摘要与 r||s||v 签名恢复签名者地址。
+func RecoverAddress(hash []byte, sig [65]byte) (Address, error) {
+	if sig[64] > 3 {
+		return Address{}, errors.New("invalid signature")
+	}
+	compact := make([]byte, 65)
+	compact[0] = compactSigMagic + sig[64]
+	copy(compact[1:], sig[:64])
+	pub, _, err := ecdsa.RecoverCompact(compact, hash)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid signature: %w", err)
+	}
+	return pubkeyAddress(pub), nil
+}
+
+func pubkeyAddress(pub *secp256k1.PublicKey) Address {
+	// SerializeUncompressed 返回 0x04||X||Y。
+	var a Address
+	copy(a[:], Keccak256(pub.SerializeUncompressed()[1:])[12:])
+	return a
+}
@@ -0,0 +1,41 @@
+package evm
+
+import (
+	"math/big"
+)
+
+// LegacyTx 是 EIP-155 重放保护下的传统交易，所有 EVM 链都接受这种格式。
+type LegacyTx struct {
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+	To       *Address
+	Value    *big.Int
+	Data     []byte
+}
+
+// SignLegacyTx 按 EIP-155 对交易签名，返回可直接用于 eth_sendRawTransaction 的 RLP 字节与交易哈希。
+func SignLegacyTx(tx LegacyTx, chainID *big.Int, key *PrivateKey) ([]byte, []byte, error) {
+	unsigned, err := EncodeRLP([]any{
+		tx.Nonce, tx.GasPrice, tx.Gas, tx.To, tx.Value, tx.Data,
+		chainID, uint64(0), uint64(0),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := key.Sign(Keccak256(unsigned))
+	if err != nil {
+		return nil, nil, err
+	}
+	// v = recid + chainId*2 + 35
+	v := new(big.Int).Mul(chainID, big.NewInt(2))
+	v.Add(v, big.NewInt(35+int64(sig[64])))
+	raw, err := EncodeRLP([]any{
+		tx.Nonce, tx.GasPrice, tx.Gas, tx.To, tx.Value, tx.Data,
+		v, new(big.Int).SetBytes(sig[0:32]), new(big.Int).SetBytes(sig[32:64]),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, Keccak256(raw), nil
+}
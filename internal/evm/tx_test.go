@@ -0,0 +1,87 @@
+package evm
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	cases := map[string]string{
+		"":                          "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		"transfer(address,uint256)": "a9059cbb2ab09eb219583f4a59a5d0623ade346d962bcd4e46b11da047c9049b",
+	}
+	for in, want := range cases {
+		if got := hex.EncodeToString(Keccak256([]byte(in))); got != want {
+			t.Errorf("Keccak256(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+// TestSignLegacyTxEIP155 使用 EIP-155 规范中的示例交易。
+func TestSignLegacyTxEIP155(t *testing.T) {
+	key, err := ParsePrivateKey("0x4646464646464646464646464646464646464646464646464646464646464646")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := key.Address().Hex(), "0x9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f"; got != want {
+		t.Fatalf("address = %s, want %s", got, want)
+	}
+	to, _ := ParseAddress("0x3535353535353535353535353535353535353535")
+	value, _ := new(big.Int).SetString("1000000000000000000", 10)
+	raw, hash, err := SignLegacyTx(LegacyTx{
+		Nonce:    9,
+		GasPrice: big.NewInt(20_000_000_000),
+		Gas:      21000,
+		To:       &to,
+		Value:    value,
+	}, big.NewInt(1), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025" +
+		"a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276" +
+		"a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+	if got := hex.EncodeToString(raw); got != want {
+		t.Fatalf("signed tx = %s\nwant        %s", got, want)
+	}
+	if got := hex.EncodeToString(hash); got != hex.EncodeToString(Keccak256(raw)) {
+		t.Fatalf("tx hash = %s", got)
+	}
+}
+
+func TestRecoverAddress(t *testing.T) {
+	key, err := ParsePrivateKey(strings.Repeat("11", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := Keccak256([]byte("executor"))
+	sig, err := key.Sign(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := RecoverAddress(digest, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != key.Address() {
+		t.Fatalf("recovered %s, want %s", got.Hex(), key.Address().Hex())
+	}
+	sig[64] = 4
+	if _, err := RecoverAddress(digest, sig); err == nil {
+		t.Fatal("expected error for invalid recovery id")
+	}
+}
+
+func TestParsePrivateKeyRange(t *testing.T) {
+	for _, s := range []string{
+		strings.Repeat("00", 32),
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+		"0x1234",
+	} {
+		if _, err := ParsePrivateKey(s); err == nil {
+			t.Errorf("ParsePrivateKey(%s) succeeded", s)
+		}
+	}
+}
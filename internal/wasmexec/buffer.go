@@ -0,0 +1,144 @@
+package wasmexec
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// buffer ABI 约定：
+//  1. host 调用 guest 导出的分配函数 alloc(len) -> ptr，把输入字节写入线性内存；
+//  2. 调用入口 entry(ptr, len) -> i64，返回值高 32 位为结果指针、低 32 位为结果长度；
+//  3. host 读出结果字节，并在 guest 导出释放函数时归还输入缓冲。
+var (
+	defaultAllocNames = []string{"alloc", "malloc", "allocate"}
+	defaultFreeNames  = []string{"dealloc", "free", "deallocate"}
+)
+
+// ErrBufferABI 表示模块的导出不符合 buffer ABI（缺少内存、分配函数或函数签名不符）。
+var ErrBufferABI = errors.New("module does not implement the buffer abi")
+
+var (
+	i32 = api.ValueTypeI32
+	i64 = api.ValueTypeI64
+)
+
+// bufferInput 从 input.json 中取出要写入 guest 内存的字节：优先 input（JSON 原文），其次 input_base64。
+func bufferInput(spec inputSpec) ([]byte, error) {
+	if len(spec.Input) > 0 {
+		return []byte(spec.Input), nil
+	}
+	if spec.InputBase64 != "" {
+		data, err := base64.StdEncoding.DecodeString(spec.InputBase64)
+		if err != nil {
+			return nil, fmt.Errorf("decode input_base64: %w", err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// callBuffer 按 buffer ABI 调用入口函数。
+func callBuffer(ctx context.Context, mod api.Module, fn api.Function, inv Invocation) (Output, error) {
+	mem := mod.Memory()
+	if mem == nil {
+		return Output{}, fmt.Errorf("%w: module exports no memory", ErrBufferABI)
+	}
+	def := fn.Definition()
+	if err := checkSignature(fn, "entry", []api.ValueType{i32, i32}, []api.ValueType{i64}); err != nil {
+		return Output{}, err
+	}
+
+	alloc := lookupExport(mod, inv.Alloc, defaultAllocNames)
+	if alloc == nil {
+		return Output{}, fmt.Errorf("%w: no exported allocator (%v)", ErrBufferABI, defaultAllocNames)
+	}
+	if err := checkSignature(alloc, "allocator", []api.ValueType{i32}, []api.ValueType{i32}); err != nil {
+		return Output{}, err
+	}
+	// free(ptr) 与 dealloc(ptr, len) 两种签名都支持。
+	free := lookupExport(mod, inv.Free, defaultFreeNames)
+	if free != nil && checkSignature(free, "deallocator", []api.ValueType{i32}, nil) != nil {
+		if err := checkSignature(free, "deallocator", []api.ValueType{i32, i32}, nil); err != nil {
+			return Output{}, err
+		}
+	}
+	size := uint64(len(inv.Input))
+	res, err := alloc.Call(ctx, size)
+	if err != nil {
+		return Output{}, fmt.Errorf("call allocator: %w", err)
+	}
+	ptr := uint32(res[0])
+	if size > 0 && !mem.Write(ptr, inv.Input) {
+		return Output{}, fmt.Errorf("write %d input bytes at %#x: out of memory range", size, ptr)
+	}
+
+	params := []uint64{uint64(ptr), size}
+	results, err := fn.Call(ctx, params...)
+	if err != nil {
+		return Output{}, fmt.Errorf("call %s failed: %w", inv.Entry, err)
+	}
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	view, ok := mem.Read(outPtr, outLen)
+	if !ok {
+		return Output{}, fmt.Errorf("read %d result bytes at %#x: out of memory range", outLen, outPtr)
+	}
+	data := append([]byte(nil), view...)
+
+	if free != nil {
+		n := len(free.Definition().ParamTypes())
+		if _, err := free.Call(ctx, []uint64{uint64(ptr), size}[:n]...); err != nil {
+			return Output{}, fmt.Errorf("free input buffer: %w", err)
+		}
+	}
+
+	out := Output{
+		Entry:   inv.Entry,
+		ABI:     ABIBuffer,
+		Args:    decodeValues(def.ParamTypes(), params),
+		Results: decodeValues(def.ResultTypes(), results),
+	}
+	if json.Valid(data) {
+		out.Output = data
+	} else {
+		out.OutputBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+	return out, nil
+}
+
+// checkSignature 校验导出函数的参数与返回值类型，不符时返回包装了 ErrBufferABI 的错误。
+func checkSignature(fn api.Function, role string, params, results []api.ValueType) error {
+	def := fn.Definition()
+	if slices.Equal(def.ParamTypes(), params) && slices.Equal(def.ResultTypes(), results) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s %v must have signature %s -> %s, got %s -> %s", ErrBufferABI, role, def.ExportNames(),
+		typeList(params), typeList(results), typeList(def.ParamTypes()), typeList(def.ResultTypes()))
+}
+
+func typeList(types []api.ValueType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = api.ValueTypeName(t)
+	}
+	return fmt.Sprintf("(%s)", strings.Join(names, ", "))
+}
+
+// lookupExport 返回指定名称的导出函数；name 为空时依次尝试候选名称。
+func lookupExport(mod api.Module, name string, candidates []string) api.Function {
+	if name != "" {
+		return mod.ExportedFunction(name)
+	}
+	for _, c := range candidates {
+		if fn := mod.ExportedFunction(c); fn != nil {
+			return fn
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package wasmexec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// badAllocModule 导出 memory、run(i32, i32) -> i64 与没有返回值的 alloc()。
+var badAllocModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// type: (i32, i32) -> i64, () -> ()
+	0x01, 0x0a, 0x02, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, 0x60, 0x00, 0x00,
+	// function: run, alloc
+	0x03, 0x03, 0x02, 0x00, 0x01,
+	// memory: 1 page
+	0x05, 0x03, 0x01, 0x00, 0x01,
+	// export: memory, run, alloc
+	0x07, 0x18, 0x03,
+	0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+	0x03, 'r', 'u', 'n', 0x00, 0x00,
+	0x05, 'a', 'l', 'l', 'o', 'c', 0x00, 0x01,
+	// code: run = i64.const 0, alloc = nop
+	0x0a, 0x09, 0x02, 0x04, 0x00, 0x42, 0x00, 0x0b, 0x02, 0x00, 0x0b,
+}
+
+func TestBufferABIRejectsAllocatorWithoutResult(t *testing.T) {
+	_, err := Run(context.Background(), badAllocModule, Invocation{Entry: "run", ABI: ABIBuffer, Input: []byte(`{}`)}, Options{})
+	if !errors.Is(err, ErrBufferABI) {
+		t.Fatalf("err = %v, want ErrBufferABI", err)
+	}
+	if !strings.Contains(err.Error(), "allocator") {
+		t.Errorf("error does not name the allocator: %v", err)
+	}
+}
+
+func TestBufferABIRejectsEntrySignature(t *testing.T) {
+	_, err := Run(context.Background(), badAllocModule, Invocation{Entry: "alloc", ABI: ABIBuffer}, Options{})
+	if !errors.Is(err, ErrBufferABI) {
+		t.Fatalf("err = %v, want ErrBufferABI", err)
+	}
+}
@@ -0,0 +1,118 @@
+package wasmexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// 执行状态，写入 result.json 的 status 字段。
+const (
+	StatusOK        = "ok"
+	StatusTimeout   = "timeout"
+	StatusOutOfFuel = "out-of-fuel"
+	StatusError     = "error"
+)
+
+var (
+	// ErrTimeout 表示执行超过 TIMEOUT_SEC。
+	ErrTimeout = errors.New("execution timed out")
+	// ErrOutOfFuel 表示 guest 函数调用次数超过 FUEL_LIMIT。
+	ErrOutOfFuel = errors.New("execution exceeded its function call budget")
+)
+
+// Limits 约束单次执行的资源。零值表示不限制。
+type Limits struct {
+	Timeout time.Duration
+	// CallBudget 是 guest 函数调用次数的上限（FUEL_LIMIT），不是指令计数：每次进入函数消耗 1 次，
+	// 可约束深递归与大量调用；不含调用的紧密循环只能由 Timeout 终止。
+	CallBudget uint64
+}
+
+// ParseLimits 从 TIMEOUT_SEC（秒，可为小数）与 FUEL_LIMIT（函数调用次数）读取执行限制。
+func ParseLimits(getenv func(string) string) (Limits, error) {
+	var l Limits
+	if v := getenv("TIMEOUT_SEC"); v != "" {
+		sec, err := strconv.ParseFloat(v, 64)
+		if err != nil || sec < 0 {
+			return l, fmt.Errorf("invalid TIMEOUT_SEC=%q", v)
+		}
+		l.Timeout = time.Duration(sec * float64(time.Second))
+	}
+	if v := getenv("FUEL_LIMIT"); v != "" {
+		calls, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return l, fmt.Errorf("invalid FUEL_LIMIT=%q: %w", v, err)
+		}
+		l.CallBudget = calls
+	}
+	return l, nil
+}
+
+// StatusOf 把 Run 返回的错误映射为 result.json 中的 status。
+func StatusOf(err error) string {
+	switch {
+	case err == nil:
+		return StatusOK
+	case errors.Is(err, ErrTimeout):
+		return StatusTimeout
+	case errors.Is(err, ErrOutOfFuel):
+		return StatusOutOfFuel
+	default:
+		return StatusError
+	}
+}
+
+// Failure 构造失败时写入 result.json 的输出。
+func Failure(entry string, err error) Output {
+	return Output{
+		Entry:   entry,
+		Args:    []Value{},
+		Results: []Value{},
+		Status:  StatusOf(err),
+		Error:   err.Error(),
+	}
+}
+
+// withLimits 返回带超时与调用计数的上下文；调用次数超过 CallBudget 时以 ErrOutOfFuel 为原因取消上下文，
+// 配合 WithCloseOnContextDone 让 wazero 在下一个检查点终止执行。
+// 计数依赖 experimental.FunctionListenerFactory 在每次进入 guest 函数时回调，解释器与编译器运行时均适用。
+func withLimits(ctx context.Context, l Limits) (context.Context, context.CancelFunc) {
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	cancel := func() { cancelCause(context.Canceled) }
+	if l.Timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, l.Timeout, ErrTimeout)
+		prev := cancel
+		cancel = func() { stop(); prev() }
+	}
+	if l.CallBudget > 0 {
+		var used atomic.Uint64
+		listener := experimental.FunctionListenerFunc(func(context.Context, api.Module, api.FunctionDefinition, []uint64, experimental.StackIterator) {
+			if used.Add(1) > l.CallBudget {
+				cancelCause(ErrOutOfFuel)
+			}
+		})
+		ctx = experimental.WithFunctionListenerFactory(ctx, experimental.FunctionListenerFactoryFunc(
+			func(api.FunctionDefinition) experimental.FunctionListener { return listener },
+		))
+	}
+	return ctx, cancel
+}
+
+// limitError 在调用失败后根据上下文取消原因归类错误。
+func limitError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, ErrTimeout) || errors.Is(cause, ErrOutOfFuel) {
+		return fmt.Errorf("%w: %v", cause, err)
+	}
+	return err
+}
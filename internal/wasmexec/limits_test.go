@@ -0,0 +1,75 @@
+package wasmexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// limitsModule 导出不含调用的死循环 spin() 与无限递归的 recurse()。
+var limitsModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// type: () -> ()
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+	// function: spin, recurse
+	0x03, 0x03, 0x02, 0x00, 0x00,
+	// export: spin, recurse
+	0x07, 0x12, 0x02,
+	0x04, 's', 'p', 'i', 'n', 0x00, 0x00,
+	0x07, 'r', 'e', 'c', 'u', 'r', 's', 'e', 0x00, 0x01,
+	// code: spin = loop br 0 end, recurse = call recurse
+	0x0a, 0x0e, 0x02,
+	0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b,
+	0x04, 0x00, 0x10, 0x01, 0x0b,
+}
+
+func TestTimeoutStopsTightLoop(t *testing.T) {
+	start := time.Now()
+	// 调用预算不会终止不含调用的循环，只能由超时终止。
+	_, err := Run(context.Background(), limitsModule, Invocation{Entry: "spin"}, Options{
+		Limits: Limits{Timeout: 200 * time.Millisecond, CallBudget: 10},
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("loop ran for %s after a 200ms timeout", elapsed)
+	}
+	if got := Failure("spin", err).Status; got != StatusTimeout {
+		t.Errorf("status = %q, want %q", got, StatusTimeout)
+	}
+}
+
+func TestCallBudgetStopsRecursion(t *testing.T) {
+	_, err := Run(context.Background(), limitsModule, Invocation{Entry: "recurse"}, Options{
+		Limits: Limits{Timeout: 10 * time.Second, CallBudget: 1000},
+	})
+	if !errors.Is(err, ErrOutOfFuel) {
+		t.Fatalf("err = %v, want ErrOutOfFuel", err)
+	}
+	if got := Failure("recurse", err).Status; got != StatusOutOfFuel {
+		t.Errorf("status = %q, want %q", got, StatusOutOfFuel)
+	}
+
+	// 未设置预算时递归以栈溢出结束，归类为普通错误。
+	_, err = Run(context.Background(), limitsModule, Invocation{Entry: "recurse"}, Options{})
+	if err == nil || StatusOf(err) != StatusError {
+		t.Fatalf("unbounded recursion: err = %v, status = %q", err, StatusOf(err))
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	env := map[string]string{"TIMEOUT_SEC": "1.5", "FUEL_LIMIT": "5000"}
+	l, err := ParseLimits(func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Timeout != 1500*time.Millisecond || l.CallBudget != 5000 {
+		t.Errorf("limits = %+v", l)
+	}
+	env["FUEL_LIMIT"] = "-1"
+	if _, err := ParseLimits(func(k string) string { return env[k] }); err == nil {
+		t.Error("negative FUEL_LIMIT accepted")
+	}
+}
@@ -0,0 +1,104 @@
+package wasmexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Value 是带 Wasm 类型标注的参数或返回值。
+// Value 字段为 JSON 数字；NaN/±Inf 无法用 JSON 数字表示，输出为字符串。Raw 保留原始 64 位编码。
+type Value struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+	Raw   uint64 `json:"raw"`
+}
+
+// encodeParams 按函数签名把 JSON 数字编码为 wazero 调用所需的 uint64。
+func encodeParams(types []api.ValueType, args []json.Number) ([]uint64, error) {
+	if len(args) != len(types) {
+		return nil, fmt.Errorf("function expects %d args, got %d", len(types), len(args))
+	}
+	out := make([]uint64, len(args))
+	for i, t := range types {
+		v, err := encodeValue(t, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("arg %d (%s): %w", i, api.ValueTypeName(t), err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func encodeValue(t api.ValueType, n json.Number) (uint64, error) {
+	s := n.String()
+	switch t {
+	case api.ValueTypeI32:
+		// 同时接受有符号与无符号写法：[-2^31, 2^32)。
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			if v < math.MinInt32 || v > math.MaxUint32 {
+				return 0, fmt.Errorf("%s out of i32 range", s)
+			}
+			return api.EncodeU32(uint32(v)), nil
+		}
+		return 0, fmt.Errorf("%q is not an integer", s)
+	case api.ValueTypeI64:
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return api.EncodeI64(v), nil
+		}
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return v, nil
+		}
+		return 0, fmt.Errorf("%q is not a 64-bit integer", s)
+	case api.ValueTypeF32:
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return 0, err
+		}
+		return api.EncodeF32(float32(f)), nil
+	case api.ValueTypeF64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, err
+		}
+		return api.EncodeF64(f), nil
+	default:
+		return 0, fmt.Errorf("unsupported value type %s", api.ValueTypeName(t))
+	}
+}
+
+// decodeValues 按类型把 wazero 的 uint64 结果解码为可读数值。
+func decodeValues(types []api.ValueType, raw []uint64) []Value {
+	out := make([]Value, len(raw))
+	for i, r := range raw {
+		t := api.ValueTypeI64
+		if i < len(types) {
+			t = types[i]
+		}
+		out[i] = Value{Type: api.ValueTypeName(t), Value: decodeValue(t, r), Raw: r}
+	}
+	return out
+}
+
+func decodeValue(t api.ValueType, r uint64) any {
+	switch t {
+	case api.ValueTypeI32:
+		return json.Number(strconv.FormatInt(int64(api.DecodeI32(r)), 10))
+	case api.ValueTypeF32:
+		return floatValue(float64(api.DecodeF32(r)), 32)
+	case api.ValueTypeF64:
+		return floatValue(api.DecodeF64(r), 64)
+	default:
+		return json.Number(strconv.FormatInt(int64(r), 10))
+	}
+}
+
+func floatValue(f float64, bits int) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, bits)
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bits))
+}
@@ -0,0 +1,236 @@
+// Package wasmexec 封装执行器与本地后端共用的 wazero 调用逻辑。
+package wasmexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// DefaultEntry 是未指定入口时调用的导出函数。
+const DefaultEntry = "add"
+
+// 调用约定。ABIScalar 直接传递数值参数；ABIBuffer 通过线性内存传递字节，见 buffer.go。
+const (
+	ABIScalar = "scalar"
+	ABIBuffer = "buffer"
+)
+
+// Invocation 描述一次导出函数调用，参数保持 JSON 数字原文，调用前再按函数签名编码。
+type Invocation struct {
+	Entry string
+	Args  []json.Number
+	// ABI 为空时等同 ABIScalar。
+	ABI string
+	// Input 是 ABIBuffer 模式下写入 guest 内存的字节。
+	Input []byte
+	// Alloc/Free 覆盖 ABIBuffer 模式使用的分配/释放导出函数名，为空时自动探测。
+	Alloc string
+	Free  string
+}
+
+// Output 对应执行器写入 result.json 的结构。
+type Output struct {
+	Entry   string  `json:"entry"`
+	Args    []Value `json:"args"`
+	Results []Value `json:"results"`
+	ABI     string  `json:"abi,omitempty"`
+	Status  string  `json:"status"`
+	Error   string  `json:"error,omitempty"`
+	// Output 在 guest 返回的字节为合法 JSON 时原样嵌入，否则使用 OutputBase64。
+	Output       json.RawMessage `json:"output,omitempty"`
+	OutputBase64 string          `json:"output_base64,omitempty"`
+}
+
+// GuestDataPath 是数据目录在模块内（WASI 预打开目录）的路径。
+const GuestDataPath = "/data"
+
+// Options 控制模块运行时的标准输出/错误去向、资源限制与数据目录。
+type Options struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Limits Limits
+	// DataDir 非空时以只读方式预打开到 GuestDataPath，模块可通过 WASI 文件接口读取。
+	DataDir string
+}
+
+type inputSpec struct {
+	Entry       string          `json:"entry"`
+	Args        []json.Number   `json:"args"`
+	ABI         string          `json:"abi"`
+	Input       json.RawMessage `json:"input"`
+	InputBase64 string          `json:"input_base64"`
+}
+
+// Run 使用 wazero + WASI 实例化模块并调用入口函数。
+// 超时或函数调用次数超过预算时返回包装了 ErrTimeout/ErrOutOfFuel 的错误，可用 StatusOf 归类。
+func Run(ctx context.Context, wasmBin []byte, inv Invocation, opts Options) (Output, error) {
+	out, err := run(ctx, wasmBin, inv, opts)
+	if err != nil {
+		return Output{}, err
+	}
+	out.Status = StatusOK
+	return out, nil
+}
+
+func run(parent context.Context, wasmBin []byte, inv Invocation, opts Options) (Output, error) {
+	ctx, cancel := withLimits(parent, opts.Limits)
+	defer cancel()
+
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	defer rt.Close(context.WithoutCancel(ctx))
+	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+
+	modCfg := wazero.NewModuleConfig().WithStartFunctions("_initialize")
+	if opts.Stdout != nil {
+		modCfg = modCfg.WithStdout(opts.Stdout)
+	}
+	if opts.Stderr != nil {
+		modCfg = modCfg.WithStderr(opts.Stderr)
+	}
+	if opts.DataDir != "" {
+		modCfg = modCfg.WithFSConfig(wazero.NewFSConfig().WithReadOnlyDirMount(opts.DataDir, GuestDataPath))
+	}
+	mod, err := rt.InstantiateWithConfig(ctx, wasmBin, modCfg)
+	if err != nil {
+		return Output{}, fmt.Errorf("instantiate wasm: %w", limitError(ctx, err))
+	}
+
+	fn := mod.ExportedFunction(inv.Entry)
+	if fn == nil {
+		return Output{}, fmt.Errorf("exported function %q not found", inv.Entry)
+	}
+	if inv.ABI == ABIBuffer {
+		out, err := callBuffer(ctx, mod, fn, inv)
+		return out, limitError(ctx, err)
+	}
+	def := fn.Definition()
+	params, err := encodeParams(def.ParamTypes(), inv.Args)
+	if err != nil {
+		return Output{}, fmt.Errorf("encode args for %s: %w", inv.Entry, err)
+	}
+	results, err := fn.Call(ctx, params...)
+	if err != nil {
+		return Output{}, fmt.Errorf("call %s failed: %w", inv.Entry, limitError(ctx, err))
+	}
+	return Output{
+		Entry:   inv.Entry,
+		Args:    decodeValues(def.ParamTypes(), params),
+		Results: decodeValues(def.ResultTypes(), results),
+	}, nil
+}
+
+// ResolveInvocation 按 input.json -> ARGS_JSON -> ARG_n -> ADD_X/ADD_Y 的顺序确定入口与参数。
+// getenv 返回去除首尾空白后的环境变量值，便于本地后端用任务参数模拟环境。
+func ResolveInvocation(input []byte, getenv func(string) string) (Invocation, error) {
+	var spec inputSpec
+	if content := strings.TrimSpace(string(input)); content != "" {
+		if err := json.Unmarshal([]byte(content), &spec); err != nil {
+			return Invocation{}, fmt.Errorf("parse input: %w", err)
+		}
+	}
+
+	entry := getenv("ENTRY")
+	if entry == "" {
+		entry = DefaultEntry
+	}
+	if spec.Entry != "" {
+		entry = spec.Entry
+	}
+	abi := getenv("ABI")
+	if spec.ABI != "" {
+		abi = spec.ABI
+	}
+	switch abi {
+	case "", ABIScalar:
+	case ABIBuffer:
+		payload, err := bufferInput(spec)
+		if err != nil {
+			return Invocation{}, err
+		}
+		return Invocation{Entry: entry, ABI: ABIBuffer, Input: payload, Alloc: getenv("ALLOC_FN"), Free: getenv("FREE_FN")}, nil
+	default:
+		return Invocation{}, fmt.Errorf("unknown abi %q", abi)
+	}
+	args := append([]json.Number{}, spec.Args...)
+	if argsJSON := getenv("ARGS_JSON"); len(args) == 0 && argsJSON != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return Invocation{}, fmt.Errorf("parse ARGS_JSON: %w", err)
+		}
+	}
+	if len(args) == 0 {
+		seq, err := sequentialArgs(getenv)
+		if err != nil {
+			return Invocation{}, err
+		}
+		args = seq
+	}
+	if len(args) == 0 {
+		legacy, err := legacyAddArgs(getenv)
+		if err != nil {
+			return Invocation{}, err
+		}
+		args = legacy
+	}
+	return Invocation{Entry: entry, Args: args}, nil
+}
+
+func sequentialArgs(getenv func(string) string) ([]json.Number, error) {
+	var args []json.Number
+	for i := 0; ; i++ {
+		candidates := []string{
+			fmt.Sprintf("ARG_%d", i),
+			fmt.Sprintf("ARG%d", i),
+		}
+		var (
+			val  string
+			name string
+		)
+		for _, c := range candidates {
+			if v := getenv(c); v != "" {
+				val = v
+				name = c
+				break
+			}
+		}
+		if val == "" {
+			break
+		}
+		n, err := parseNumber(val, name)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, n)
+	}
+	return args, nil
+}
+
+func legacyAddArgs(getenv func(string) string) ([]json.Number, error) {
+	x := getenv("ADD_X")
+	y := getenv("ADD_Y")
+	if x == "" || y == "" {
+		return nil, nil
+	}
+	nx, err := parseNumber(x, "ADD_X")
+	if err != nil {
+		return nil, err
+	}
+	ny, err := parseNumber(y, "ADD_Y")
+	if err != nil {
+		return nil, err
+	}
+	return []json.Number{nx, ny}, nil
+}
+
+func parseNumber(val, name string) (json.Number, error) {
+	if _, err := strconv.ParseFloat(val, 64); err != nil {
+		return "", fmt.Errorf("invalid %s=%q: %w", name, val, err)
+	}
+	return json.Number(val), nil
+}